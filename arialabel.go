@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ariaLocale describes the ariaLabel format of one YouTube UI language. The
+// rest of a label (after the title) is "<by> <channel> <views> <age> <duration>",
+// e.g. "by Derek Banas 1,743,455 views 10 years ago 41 minutes".
+type ariaLocale struct {
+	Code string
+
+	// By comes before the channel name
+	By string
+
+	// Views are the words after the view count, singular then plural, and
+	// NoViews replaces the count and the word when there are none
+	Views   []string
+	NoViews string
+
+	// SpaceGrouped is set when counts are grouped with plain spaces, e.g.
+	// "1 743 455"; commas, dots and no-break spaces always work
+	SpaceGrouped bool
+
+	// Age is a pattern with groups n and unit, and streamed when the video
+	// was a live stream, e.g. "(?P<streamed>Streamed )?(?P<n>\d+) (?P<unit>\pL+) ago"
+	Age string
+
+	// Units maps the lower-case time words used in ages and durations to
+	// year, month, week, day, hour, minute or second
+	Units map[string]string
+
+	// pattern is compiled from the above by compileAriaLocales
+	pattern *regexp.Regexp
+}
+
+// ariaLocales are the YouTube UI languages ariaLabels can be parsed in. They
+// are tried in order and the first whose pattern matches is used.
+var ariaLocales = compileAriaLocales([]ariaLocale{
+	{
+		Code: "en", By: "by", Views: []string{"view", "views"}, NoViews: "No views",
+		Age: `(?P<streamed>Streamed )?(?P<n>\d+) (?P<unit>\pL+) ago`,
+		Units: map[string]string{
+			"year": "year", "years": "year", "month": "month", "months": "month", "week": "week", "weeks": "week",
+			"day": "day", "days": "day", "hour": "hour", "hours": "hour", "minute": "minute", "minutes": "minute",
+			"second": "second", "seconds": "second",
+		},
+	},
+	{
+		Code: "de", By: "von", Views: []string{"Aufruf", "Aufrufe"}, NoViews: "Keine Aufrufe",
+		Age: `(?P<streamed>Live übertragen )?vor (?P<n>\d+) (?P<unit>\pL+)`,
+		Units: map[string]string{
+			"jahr": "year", "jahren": "year", "monat": "month", "monaten": "month", "woche": "week", "wochen": "week",
+			"tag": "day", "tagen": "day", "stunde": "hour", "stunden": "hour", "minute": "minute", "minuten": "minute",
+			"sekunde": "second", "sekunden": "second",
+		},
+	},
+	{
+		Code: "es", By: "de", Views: []string{"visualización", "visualizaciones", "vista", "vistas"}, NoViews: "Sin visualizaciones",
+		Age: `(?P<streamed>Emitido )?hace (?P<n>\d+) (?P<unit>\pL+)`,
+		Units: map[string]string{
+			"año": "year", "años": "year", "mes": "month", "meses": "month", "semana": "week", "semanas": "week",
+			"día": "day", "días": "day", "hora": "hour", "horas": "hour", "minuto": "minute", "minutos": "minute",
+			"segundo": "second", "segundos": "second",
+		},
+	},
+	{
+		Code: "pt", By: "de", Views: []string{"visualização", "visualizações"}, NoViews: "Nenhuma visualização",
+		Age: `(?P<streamed>Transmitido )?há (?P<n>\d+) (?P<unit>\pL+)`,
+		Units: map[string]string{
+			"ano": "year", "anos": "year", "mês": "month", "meses": "month", "semana": "week", "semanas": "week",
+			"dia": "day", "dias": "day", "hora": "hour", "horas": "hour", "minuto": "minute", "minutos": "minute",
+			"segundo": "second", "segundos": "second",
+		},
+	},
+	{
+		Code: "fr", By: "de", Views: []string{"vue", "vues"}, NoViews: "Aucune vue", SpaceGrouped: true,
+		Age: `(?P<streamed>Diffusé )?il y a (?P<n>\d+) (?P<unit>\pL+)`,
+		Units: map[string]string{
+			"an": "year", "ans": "year", "mois": "month", "semaine": "week", "semaines": "week",
+			"jour": "day", "jours": "day", "heure": "hour", "heures": "hour", "minute": "minute", "minutes": "minute",
+			"seconde": "second", "secondes": "second",
+		},
+	},
+	{
+		Code: "it", By: "di", Views: []string{"visualizzazione", "visualizzazioni"}, NoViews: "Nessuna visualizzazione",
+		Age: `(?P<streamed>Trasmesso in streaming )?(?P<n>\d+) (?P<unit>\pL+) fa`,
+		Units: map[string]string{
+			"anno": "year", "anni": "year", "mese": "month", "mesi": "month", "settimana": "week", "settimane": "week",
+			"giorno": "day", "giorni": "day", "ora": "hour", "ore": "hour", "minuto": "minute", "minuti": "minute",
+			"secondo": "second", "secondi": "second",
+		},
+	},
+	{
+		Code: "nl", By: "door", Views: []string{"weergave", "weergaven"}, NoViews: "Geen weergaven",
+		Age: `(?P<streamed>Gestreamd )?(?P<n>\d+) (?P<unit>\pL+) geleden`,
+		Units: map[string]string{
+			"jaar": "year", "maand": "month", "maanden": "month", "week": "week", "weken": "week",
+			"dag": "day", "dagen": "day", "uur": "hour", "minuut": "minute", "minuten": "minute",
+			"seconde": "second", "seconden": "second",
+		},
+	},
+})
+
+// compileAriaLocales builds each locale's pattern
+func compileAriaLocales(locales []ariaLocale) []ariaLocale {
+	for i, locale := range locales {
+		var views []string
+		for _, word := range locale.Views {
+			views = append(views, regexp.QuoteMeta(word))
+		}
+		separators := `.,\x{a0}\x{202f}`
+		if locale.SpaceGrouped {
+			separators += " "
+		}
+		count := fmt.Sprintf(`\d{1,3}(?:[%s]\d{3})*|\d+`, separators)
+		locales[i].pattern = regexp.MustCompile(fmt.Sprintf(`^%s (?P<channel>.+?) (?:(?P<views>%s) (?:%s)|%s) (?P<age>%s)\s*(?P<duration>.*)$`,
+			regexp.QuoteMeta(locale.By), count, strings.Join(views, "|"), regexp.QuoteMeta(locale.NoViews), locale.Age))
+	}
+	return locales
+}
+
+// durationPartPattern matches the pieces of a spoken duration such as
+// "1 hour, 2 minutes, 3 seconds" or "1 Stunde und 2 Minuten"
+var durationPartPattern = regexp.MustCompile(`(\d+) (\pL+)`)
+
+// parseAriaLabel fills in channel, views, age and duration from the video's
+// ariaLabel, in whichever UI language it is written, and returns that
+// language. Ages are stored in English, e.g. "3 years ago", so they read the
+// same whatever the language. Labels that don't follow a known format are
+// left alone and "" is returned.
+func parseAriaLabel(video *Video) string {
+	rest := strings.TrimSpace(strings.TrimPrefix(video.AriaLabel, video.Title))
+	for _, locale := range ariaLocales {
+		match := locale.pattern.FindStringSubmatch(rest)
+		if match == nil {
+			continue
+		}
+		group := func(name string) string { return match[locale.pattern.SubexpIndex(name)] }
+
+		video.Channel = group("channel")
+		video.Views = 0
+		if digits := strings.Map(keepDigits, group("views")); digits != "" {
+			if views, err := strconv.ParseInt(digits, 10, 64); err == nil {
+				video.Views = views
+			}
+		}
+		video.Age = englishAge(locale, group("n"), group("unit"), group("streamed") != "")
+		video.DurationSeconds = parseSpokenDuration(group("duration"), locale.Units)
+		return locale.Code
+	}
+	return ""
+}
+
+// keepDigits is a strings.Map function that drops everything but 0-9
+func keepDigits(r rune) rune {
+	if r >= '0' && r <= '9' {
+		return r
+	}
+	return -1
+}
+
+// englishAge writes an age such as "vor 3 Jahren" as "3 years ago"
+func englishAge(locale ariaLocale, n, unit string, streamed bool) string {
+	english := locale.Units[strings.ToLower(unit)]
+	if english == "" {
+		english = unit
+	}
+	if n != "1" {
+		english += "s"
+	}
+	age := n + " " + english + " ago"
+	if streamed {
+		age = "Streamed " + age
+	}
+	return age
+}
+
+// parseSpokenDuration converts "1 hour, 2 minutes" style durations into
+// seconds, using units to translate the time words
+func parseSpokenDuration(text string, units map[string]string) int {
+	seconds := 0
+	for _, part := range durationPartPattern.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.Atoi(part[1])
+		switch units[strings.ToLower(part[2])] {
+		case "hour":
+			seconds += n * 3600
+		case "minute":
+			seconds += n * 60
+		case "second":
+			seconds += n
+		}
+	}
+	return seconds
+}
+
+// formatDuration renders seconds as h:mm:ss or m:ss
+func formatDuration(seconds int) string {
+	if seconds <= 0 {
+		return "?"
+	}
+	h, m, s := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseAriaLabel parses every label in testdata/arialabels (one file per
+// locale, named after its code, each a list of videos with the fields the
+// label should give)
+func TestParseAriaLabel(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "arialabels", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no fixtures in testdata/arialabels")
+	}
+
+	for _, file := range files {
+		code := strings.TrimSuffix(filepath.Base(file), ".json")
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tests []Video
+		if err := json.Unmarshal(bytes, &tests); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+
+		for _, want := range tests {
+			t.Run(code+"/"+want.Title, func(t *testing.T) {
+				got := Video{Title: want.Title, AriaLabel: want.AriaLabel}
+				if locale := parseAriaLabel(&got); locale != code {
+					t.Errorf("locale = %q, want %q", locale, code)
+				}
+				if got.Channel != want.Channel {
+					t.Errorf("channel = %q, want %q", got.Channel, want.Channel)
+				}
+				if got.Views != want.Views {
+					t.Errorf("views = %d, want %d", got.Views, want.Views)
+				}
+				if got.Age != want.Age {
+					t.Errorf("age = %q, want %q", got.Age, want.Age)
+				}
+				if got.DurationSeconds != want.DurationSeconds {
+					t.Errorf("duration = %ds, want %ds", got.DurationSeconds, want.DurationSeconds)
+				}
+			})
+		}
+	}
+}
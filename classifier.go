@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// bayesModelVersion is bumped when the model file format changes
+const bayesModelVersion = 1
+
+// ClassifierConfig turns on the naive Bayes classifier in rules.json
+type ClassifierConfig struct {
+	// Model is the file written by the train command
+	Model string `json:"model"`
+
+	// Mode is "fallback" (the default) to only classify videos no keyword
+	// matched, or "primary" to let the classifier overrule the keywords
+	Mode string `json:"mode,omitempty"`
+
+	// MinConfidence is the probability a prediction needs to be used, 0.5 by default
+	MinConfidence float64 `json:"minConfidence,omitempty"`
+}
+
+// validate checks the mode and confidence
+func (c *ClassifierConfig) validate() error {
+	switch {
+	case c.Model == "":
+		return fmt.Errorf("classifier needs a model file")
+	case c.Mode != "" && c.Mode != "fallback" && c.Mode != "primary":
+		return fmt.Errorf("classifier mode must be fallback or primary, not %q", c.Mode)
+	case c.MinConfidence < 0 || c.MinConfidence > 1:
+		return fmt.Errorf("classifier minConfidence must be between 0 and 1")
+	}
+	return nil
+}
+
+// minConfidence returns MinConfidence or its default
+func (c *ClassifierConfig) minConfidence() float64 {
+	if c.MinConfidence == 0 {
+		return 0.5
+	}
+	return c.MinConfidence
+}
+
+// bayesModel is a multinomial naive Bayes model over the words of a video's
+// weighted fields, persisted as JSON
+type bayesModel struct {
+	Version   int                           `json:"version"`
+	TrainedAt string                        `json:"trainedAt"`
+	Documents map[string]int                `json:"documents"`
+	Tokens    map[string]map[string]float64 `json:"tokens"`
+
+	// Worked out by prepare
+	categories []string
+	totals     map[string]float64
+	vocabulary map[string]bool
+}
+
+// classifierStopWords are too common to say anything about a category
+var classifierStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "how": true, "in": true, "is": true, "it": true, "my": true, "of": true,
+	"on": true, "or": true, "the": true, "this": true, "to": true, "vs": true, "what": true, "why": true,
+	"with": true, "you": true, "your": true,
+}
+
+// tokenize splits text into lower-case words, keeping + and # for names like c++ and c#
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#'
+	})
+	var tokens []string
+	for _, word := range words {
+		if len(word) > 1 && !classifierStopWords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// videoTokens counts the words in each weighted field of a video, scaled by
+// the field's weight. The channel counts as a single token.
+func videoTokens(video Video, fields map[string]float64) map[string]float64 {
+	tokens := make(map[string]float64)
+	for _, field := range ruleFields {
+		weight := fields[field]
+		if weight == 0 {
+			continue
+		}
+		if field == "channel" {
+			if video.Channel != "" {
+				tokens["channel:"+strings.ToLower(video.Channel)] += weight
+			}
+			continue
+		}
+		for _, token := range tokenize(fieldText(video, field)) {
+			tokens[token] += weight
+		}
+	}
+	return tokens
+}
+
+// trainBayesModel builds a model from videos labelled with their category
+func trainBayesModel(videos []Video, labels []string, fields map[string]float64) (*bayesModel, error) {
+	model := &bayesModel{
+		Version:   bayesModelVersion,
+		TrainedAt: now(),
+		Documents: make(map[string]int),
+		Tokens:    make(map[string]map[string]float64),
+	}
+	for i, video := range videos {
+		category := labels[i]
+		model.Documents[category]++
+		if model.Tokens[category] == nil {
+			model.Tokens[category] = make(map[string]float64)
+		}
+		for token, count := range videoTokens(video, fields) {
+			model.Tokens[category][token] += count
+		}
+	}
+	if len(model.Documents) < 2 {
+		return nil, fmt.Errorf("need labelled videos in at least two categories, found %d", len(model.Documents))
+	}
+	model.prepare()
+	return model, nil
+}
+
+// prepare works out the totals predict needs
+func (m *bayesModel) prepare() {
+	m.categories = m.categories[:0]
+	m.totals = make(map[string]float64)
+	m.vocabulary = make(map[string]bool)
+	for category := range m.Documents {
+		m.categories = append(m.categories, category)
+		for token, count := range m.Tokens[category] {
+			m.totals[category] += count
+			m.vocabulary[token] = true
+		}
+	}
+	sort.Strings(m.categories)
+}
+
+// predict returns the most likely category for a video and its probability,
+// or "" if none of the video's words were seen in training
+func (m *bayesModel) predict(video Video, fields map[string]float64) (string, float64) {
+	tokens := videoTokens(video, fields)
+	for token := range tokens {
+		if !m.vocabulary[token] {
+			delete(tokens, token)
+		}
+	}
+	if len(tokens) == 0 || len(m.categories) < 2 {
+		return "", 0
+	}
+
+	documents := 0
+	for _, n := range m.Documents {
+		documents += n
+	}
+
+	// Log probabilities with add-one smoothing
+	logProbs := make([]float64, len(m.categories))
+	best := 0
+	for i, category := range m.categories {
+		logProb := math.Log(float64(m.Documents[category]) / float64(documents))
+		denominator := m.totals[category] + float64(len(m.vocabulary))
+		for token, count := range tokens {
+			logProb += count * math.Log((m.Tokens[category][token]+1)/denominator)
+		}
+		logProbs[i] = logProb
+		if logProb > logProbs[best] {
+			best = i
+		}
+	}
+
+	// Normalise to get the winner's probability
+	sum := 0.0
+	for _, logProb := range logProbs {
+		sum += math.Exp(logProb - logProbs[best])
+	}
+	return m.categories[best], 1 / sum
+}
+
+// loadBayesModel reads a model written by saveBayesModel
+func loadBayesModel(filename string) (*bayesModel, error) {
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var model bayesModel
+	if err := json.Unmarshal(bytes, &model); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if model.Version != bayesModelVersion {
+		return nil, fmt.Errorf("%s: model version %d, expected %d; run the train command again", filename, model.Version, bayesModelVersion)
+	}
+	model.prepare()
+	return &model, nil
+}
+
+// saveBayesModel writes a model as JSON
+func saveBayesModel(filename string, model *bayesModel) error {
+	bytes, err := json.MarshalIndent(model, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, bytes, 0644)
+}
+
+// trainingVideos returns the videos to train on and their categories: every
+// video with an override other than exclude (kept or changed in the review
+// UI, the dashboard or with the override command), plus, with withRules,
+// Watch Later videos the keyword rules matched
+func trainingVideos(lib *Library, rules *Rules, withRules bool) ([]Video, []string, error) {
+	overrides, err := lib.overrides()
+	if err != nil {
+		return nil, nil, err
+	}
+	overridden, err := lib.overriddenVideos()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var videos []Video
+	var labels []string
+	for _, video := range overridden {
+		category := overrides[videoKey(video)]
+		if validOverride(rules, category) && category != excludeCategory {
+			videos = append(videos, video)
+			labels = append(labels, category)
+		}
+	}
+
+	if withRules {
+		current, err := lib.watchLaterVideos()
+		if err != nil {
+			return nil, nil, err
+		}
+		keywordsOnly := &Rules{Fields: rules.Fields, Categories: rules.Categories}
+		for _, catVideos := range categorizeVideos(current, keywordsOnly, nil) {
+			if catVideos.Category == otherCategory {
+				continue
+			}
+			for _, video := range catVideos.Videos {
+				if _, ok := overrides[videoKey(video)]; !ok {
+					videos = append(videos, video)
+					labels = append(labels, catVideos.Category)
+				}
+			}
+		}
+	}
+	return videos, labels, nil
+}
+
+// runTrain implements the `train` command
+func runTrain(args []string) {
+	flags := flag.NewFlagSet("train", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	modelPath := flags.String("model", "", "file to write the model to (default the classifier model in rules.json, or model.json)")
+	withRules := flags.Bool("with-rules", false, "also learn from Watch Later videos the keyword rules matched, not just overrides")
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+	if *modelPath == "" {
+		*modelPath = "model.json"
+		if rules.Classifier != nil {
+			*modelPath = rules.Classifier.Model
+		}
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, labels, err := trainingVideos(lib, rules, *withRules)
+	if err != nil {
+		log.Fatalf("Error reading training videos: %v", err)
+	}
+	model, err := trainBayesModel(videos, labels, rules.Fields)
+	if err != nil {
+		log.Fatalf("Error training classifier: %v", err)
+	}
+
+	for _, category := range model.categories {
+		fmt.Printf("Category: %s, Training videos: %d\n", category, model.Documents[category])
+	}
+	fmt.Printf("Trained on %d videos with a vocabulary of %d words\n", len(videos), len(model.vocabulary))
+
+	if err := saveBayesModel(*modelPath, model); err != nil {
+		log.Fatalf("Error saving model: %v", err)
+	}
+	fmt.Printf("Model saved to %s\n", *modelPath)
+	if rules.Classifier == nil {
+		fmt.Printf("Add \"classifier\": {\"model\": %q} to %s to use it\n", *modelPath, *rulesPath)
+	}
+}
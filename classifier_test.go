@@ -0,0 +1,79 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("How to learn C++ and C# in 10 minutes: the Go way!")
+	want := []string{"learn", "c++", "c#", "10", "minutes", "go", "way"}
+	if len(got) != len(want) {
+		t.Fatalf("tokenize = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("token %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBayesModelPredicts(t *testing.T) {
+	fields := map[string]float64{"title": 1, "channel": 2}
+	video := func(title, channel string) Video {
+		return Video{Title: title, Channel: channel}
+	}
+	videos := []Video{
+		video("Helm charts explained", "TechWorld"),
+		video("Kubernetes pods and services", "TechWorld"),
+		video("Kubernetes operators in practice", "KubeCon"),
+		video("SQL joins explained", "DB Guru"),
+		video("Postgres indexes deep dive", "DB Guru"),
+	}
+	labels := []string{"Kubernetes", "Kubernetes", "Kubernetes", "Databases", "Databases"}
+
+	model, err := trainBayesModel(videos, labels, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		video Video
+		want  string
+	}{
+		{video("Kubernetes networking", ""), "Kubernetes"},
+		{video("Postgres vacuum", ""), "Databases"},
+		{video("Something new", "DB Guru"), "Databases"},
+		{video("Sourdough bread", ""), ""},
+	}
+	for _, tt := range tests {
+		category, probability := model.predict(tt.video, fields)
+		if category != tt.want {
+			t.Errorf("predict(%q, %q) = %q, want %q", tt.video.Title, tt.video.Channel, category, tt.want)
+		}
+		if tt.want != "" && (probability <= 0.5 || probability > 1) {
+			t.Errorf("predict(%q) probability = %v, want above 0.5", tt.video.Title, probability)
+		}
+	}
+
+	// A saved model predicts the same after loading
+	filename := filepath.Join(t.TempDir(), "classifier.json")
+	if err := saveBayesModel(filename, model); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadBayesModel(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, wantProbability := model.predict(tests[0].video, fields)
+	if got, probability := loaded.predict(tests[0].video, fields); got != want || probability != wantProbability {
+		t.Errorf("loaded model predicts %q (%v), want %q (%v)", got, probability, want, wantProbability)
+	}
+}
+
+func TestTrainBayesModelNeedsTwoCategories(t *testing.T) {
+	videos := []Video{{Title: "Helm charts"}, {Title: "Kubernetes pods"}}
+	if _, err := trainBayesModel(videos, []string{"Kubernetes", "Kubernetes"}, map[string]float64{"title": 1}); err == nil {
+		t.Error("trained a model from a single category")
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the columns of the CSV and TSV exports. The import only
+// needs id and category; the others are there to triage by.
+var csvColumns = []string{"id", "title", "link", "channel", "duration", "duration_seconds", "views", "category", "matched_keywords", "score", "override"}
+
+// utf8BOM lets spreadsheet apps detect that a CSV file is UTF-8
+const utf8BOM = "\ufeff"
+
+// formulaPrefixes start a formula in spreadsheet apps, so text cells starting
+// with one are exported behind a ' to be shown as text instead of run
+const formulaPrefixes = "=+-@"
+
+// spreadsheetText protects a text cell from being read as a formula
+func spreadsheetText(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// unquoteSpreadsheetText undoes spreadsheetText on an imported cell
+func unquoteSpreadsheetText(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// writeCSVReport writes one row per video, for triage in a spreadsheet
+func writeCSVReport(w io.Writer, report categorizedReport) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	return writeDelimitedReport(csv.NewWriter(w), report.Categories)
+}
+
+// writeTSVReport is writeCSVReport with tabs, for pasting into a spreadsheet
+func writeTSVReport(w io.Writer, report categorizedReport) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = '\t'
+	return writeDelimitedReport(writer, report.Categories)
+}
+
+// writeDelimitedReport writes the header and a row per video. Text cells that
+// would start a formula are prefixed with ', see spreadsheetText.
+func writeDelimitedReport(writer *csv.Writer, categorizedVideos []CategorizedVideos) error {
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, catVideos := range categorizedVideos {
+		for _, video := range catVideos.Videos {
+			row := []string{
+				spreadsheetText(videoKey(video)),
+				spreadsheetText(video.Title),
+				spreadsheetText(video.Link),
+				spreadsheetText(video.Channel),
+				formatDuration(video.DurationSeconds),
+				strconv.Itoa(video.DurationSeconds),
+				strconv.FormatInt(video.Views, 10),
+				spreadsheetText(catVideos.Category),
+				spreadsheetText(strings.Join(video.MatchedKeywords, "; ")),
+				strconv.FormatFloat(video.Score, 'f', -1, 64),
+				spreadsheetText(video.Override),
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvImportResult counts what importing a spreadsheet changed
+type csvImportResult struct {
+	Set, Cleared, Unchanged int
+	Skipped                 []string
+}
+
+// importedCategory is a category assigned to a video in an imported file
+type importedCategory struct {
+	Where    string // e.g. "line 3", for error messages
+	VideoID  string
+	Category string
+}
+
+// readCSVCategories reads the id and category columns of a CSV or TSV file
+// (by extension), wherever they are
+func readCSVCategories(filename string) ([]importedCategory, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	if bom, err := r.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+		r.Discard(len(utf8BOM))
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	if strings.EqualFold(filepath.Ext(filename), ".tsv") {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %v", err)
+	}
+	idColumn, categoryColumn := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "id":
+			idColumn = i
+		case "category":
+			categoryColumn = i
+		}
+	}
+	if idColumn < 0 || categoryColumn < 0 {
+		return nil, fmt.Errorf("%s needs id and category columns", filename)
+	}
+
+	var categories []importedCategory
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return categories, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		imported := importedCategory{Where: fmt.Sprintf("line %d", line)}
+		if idColumn < len(row) && categoryColumn < len(row) {
+			imported.VideoID = unquoteSpreadsheetText(strings.TrimSpace(row[idColumn]))
+			imported.Category = unquoteSpreadsheetText(strings.TrimSpace(row[categoryColumn]))
+		}
+		categories = append(categories, imported)
+	}
+}
+
+// readJSONCategories reads the category of every video in categorized_videos.json
+func readJSONCategories(filename string) ([]importedCategory, error) {
+	file, err := readCategorizedVideos(filename)
+	if err != nil {
+		return nil, err
+	}
+	var categories []importedCategory
+	for i, video := range file.Videos {
+		categories = append(categories, importedCategory{
+			Where:    fmt.Sprintf("video %d", i+1),
+			VideoID:  videoKey(video.Video),
+			Category: video.Category,
+		})
+	}
+	return categories, nil
+}
+
+// importOverrides reads categories from a CSV or TSV spreadsheet, or from an
+// edited categorized_videos.json, and applies them back as overrides: a
+// category that differs from what the rules pick becomes an override, and a
+// category that agrees with the rules clears any override.
+func importOverrides(lib *Library, rules *Rules, filename string) (csvImportResult, error) {
+	var result csvImportResult
+
+	read := readCSVCategories
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		read = readJSONCategories
+	}
+	imported, err := read(filename)
+	if err != nil {
+		return result, err
+	}
+
+	// Every video in Watch Later, ignored ones included, and what the rules
+	// alone would pick for it ("" when the ignore rules leave it out)
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		return result, err
+	}
+	ruleCategories := make(map[string]string)
+	for _, video := range videos {
+		ruleCategories[videoKey(video)] = ""
+	}
+	for _, catVideos := range categorizeVideos(videos, rules, nil) {
+		for _, video := range catVideos.Videos {
+			ruleCategories[videoKey(video)] = catVideos.Category
+		}
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		return result, err
+	}
+
+	for _, row := range imported {
+		ruleCategory, known := ruleCategories[row.VideoID]
+		switch {
+		case row.VideoID == "" && row.Category == "":
+			continue
+		case !known:
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %q is not in Watch Later", row.Where, row.VideoID))
+			continue
+		case !validOverride(rules, row.Category):
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: unknown category %q", row.Where, row.Category))
+			continue
+		}
+
+		override, hasOverride := overrides[row.VideoID]
+		switch {
+		case row.Category == ruleCategory && hasOverride:
+			if _, err := lib.clearOverride(row.VideoID); err != nil {
+				return result, err
+			}
+			result.Cleared++
+		case row.Category == ruleCategory || row.Category == override:
+			result.Unchanged++
+		default:
+			if err := lib.setOverride(row.VideoID, row.Category); err != nil {
+				return result, err
+			}
+			result.Set++
+		}
+	}
+	return result, nil
+}
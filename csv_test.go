@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSVExportQuotesFormulas(t *testing.T) {
+	video := testVideo("-aaaaaaaaaa", "=HYPERLINK(\"http://evil.example\")")
+	video.Channel = "@handle"
+	video.MatchedKeywords = []string{"+1"}
+	categorized := []CategorizedVideos{{Category: "Kubernetes", Videos: []Video{video}}}
+
+	var b bytes.Buffer
+	if err := writeDelimitedReport(csv.NewWriter(&b), categorized); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := rows[1]
+	want := map[int]string{
+		0: "'-aaaaaaaaaa",
+		1: "'=HYPERLINK(\"http://evil.example\")",
+		3: "'@handle",
+		7: "Kubernetes",
+		8: "'+1",
+	}
+	for column, value := range want {
+		if row[column] != value {
+			t.Errorf("%s = %q, want %q", csvColumns[column], row[column], value)
+		}
+	}
+
+	// The import reads the ID back without the quote
+	filename := filepath.Join(t.TempDir(), "categorized_videos.csv")
+	var export bytes.Buffer
+	if err := writeCSVReport(&export, categorizedReport{Categories: categorized}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, export.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := readCSVCategories(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0].VideoID != "-aaaaaaaaaa" || imported[0].Category != "Kubernetes" {
+		t.Errorf("imported %+v", imported)
+	}
+}
+
+func TestImportOverridesFromVersion1JSON(t *testing.T) {
+	lib := testLibrary(t)
+	helm := testVideo("aaaaaaaaaaa", "Helm charts")
+	joins := testVideo("bbbbbbbbbbb", "SQL joins")
+	if err := lib.importScrape([]Video{helm, joins}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rules := &Rules{
+		Fields:     map[string]float64{"title": 1},
+		Categories: []CategoryRule{{Name: "Kubernetes", Keywords: []string{"helm"}}, {Name: "Databases", Keywords: []string{"sql"}}},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 1 was a bare array of categories whose videos had no id
+	filename := filepath.Join(t.TempDir(), "categorized_videos.json")
+	v1 := `[
+  {"category": "Kubernetes", "videos": [{"title": "Helm charts", "link": "https://www.youtube.com/watch?v=aaaaaaaaaaa", "ariaLabel": ""}]},
+  {"category": "Kubernetes", "videos": [{"title": "SQL joins", "link": "https://www.youtube.com/watch?v=bbbbbbbbbbb&t=30s", "ariaLabel": ""}]}
+]`
+	if err := os.WriteFile(filename, []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := importOverrides(lib, rules, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Set != 1 || result.Unchanged != 1 || len(result.Skipped) != 0 {
+		t.Errorf("result = %+v, want 1 set and 1 unchanged", result)
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overrides[joins.ID] != "Kubernetes" {
+		t.Errorf("overrides = %v", overrides)
+	}
+}
+
+func TestCSVRoundTripKeepsIgnoredOverriddenVideos(t *testing.T) {
+	lib := testLibrary(t)
+	helm := testVideo("aaaaaaaaaaa", "Helm charts")
+	stream := testVideo("bbbbbbbbbbb", "Kubernetes live stream")
+	if err := lib.importScrape([]Video{helm, stream}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := lib.setOverride(stream.ID, "Kubernetes"); err != nil {
+		t.Fatal(err)
+	}
+	rules := &Rules{
+		Fields:     map[string]float64{"title": 1},
+		Categories: []CategoryRule{{Name: "Kubernetes", Keywords: []string{"helm"}}},
+		Ignore:     &IgnoreRules{TitlePatterns: []string{"live stream"}},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export bytes.Buffer
+	if err := writeCSVReport(&export, categorizedReport{Categories: categorizeVideos(videos, rules, overrides)}); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "categorized_videos.csv")
+	if err := os.WriteFile(filename, export.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := importOverrides(lib, rules, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unchanged != 2 || result.Set != 0 || result.Cleared != 0 || len(result.Skipped) != 0 {
+		t.Errorf("result = %+v, want both videos unchanged", result)
+	}
+}
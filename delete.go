@@ -1,3 +1,6 @@
+//go:build ignore
+
+// delete.go is a standalone helper, run it with `go run delete.go`
 package main
 
 import (
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kMeansRuns is how many times discoverTopics clusters from different
+// starting points before keeping the best result
+const kMeansRuns = 20
+
+// sparseVector is a TF-IDF vector keyed by term
+type sparseVector map[string]float64
+
+// dot returns the dot product of two vectors
+func (v sparseVector) dot(other sparseVector) float64 {
+	if len(other) < len(v) {
+		v, other = other, v
+	}
+	sum := 0.0
+	for term, weight := range v {
+		sum += weight * other[term]
+	}
+	return sum
+}
+
+// normalize scales the vector to unit length
+func (v sparseVector) normalize() {
+	norm := math.Sqrt(v.dot(v))
+	if norm == 0 {
+		return
+	}
+	for term := range v {
+		v[term] /= norm
+	}
+}
+
+// topicCluster is a group of similar videos and the terms that set it apart
+type topicCluster struct {
+	Videos   []Video
+	Terms    []string
+	Cohesion float64
+}
+
+// topicTerms returns the words of a video's title and tags, plus pairs of
+// adjacent title words so names like "home assistant" can become keywords
+func topicTerms(video Video) []string {
+	words := tokenize(video.Title)
+	terms := append([]string{}, words...)
+	for i := 1; i < len(words); i++ {
+		terms = append(terms, words[i-1]+" "+words[i])
+	}
+	for _, tag := range video.Tags {
+		terms = append(terms, tokenize(tag)...)
+	}
+	return terms
+}
+
+// tfidfVectors builds unit TF-IDF vectors for the videos, ignoring terms
+// that appear in fewer than minDocs videos. Videos left with no terms get a
+// nil vector.
+func tfidfVectors(videos []Video, minDocs int) []sparseVector {
+	counts := make([]map[string]float64, len(videos))
+	documentFrequency := make(map[string]int)
+	for i, video := range videos {
+		counts[i] = make(map[string]float64)
+		for _, term := range topicTerms(video) {
+			counts[i][term]++
+		}
+		for term := range counts[i] {
+			documentFrequency[term]++
+		}
+	}
+
+	vectors := make([]sparseVector, len(videos))
+	n := float64(len(videos))
+	for i := range videos {
+		vector := make(sparseVector)
+		for term, count := range counts[i] {
+			if df := documentFrequency[term]; df >= minDocs {
+				vector[term] = count * (math.Log((1+n)/(1+float64(df))) + 1)
+			}
+		}
+		if len(vector) > 0 {
+			vector.normalize()
+			vectors[i] = vector
+		}
+	}
+	return vectors
+}
+
+// kMeans clusters unit vectors by cosine similarity into k groups, starting
+// from k-means++ seeds. It returns each vector's cluster and the total
+// similarity of the vectors to their centroids, which is higher the better
+// the clustering.
+func kMeans(vectors []sparseVector, k int, random *rand.Rand) ([]int, float64) {
+	// k-means++: each new seed is picked with probability proportional to
+	// its distance from the nearest seed so far
+	centroids := []sparseVector{vectors[random.IntN(len(vectors))]}
+	distances := make([]float64, len(vectors))
+	for len(centroids) < k {
+		total := 0.0
+		for i, vector := range vectors {
+			nearest := math.Inf(1)
+			for _, centroid := range centroids {
+				nearest = min(nearest, 1-vector.dot(centroid))
+			}
+			distances[i] = max(nearest, 0)
+			total += distances[i]
+		}
+		if total == 0 {
+			break // every vector is the same as a seed
+		}
+		target := random.Float64() * total
+		for i, distance := range distances {
+			target -= distance
+			if target <= 0 {
+				centroids = append(centroids, vectors[i])
+				break
+			}
+		}
+	}
+
+	assignments := make([]int, len(vectors))
+	total := 0.0
+	for iteration := 0; iteration < 100; iteration++ {
+		changed := iteration == 0
+		total = 0
+		for i, vector := range vectors {
+			best, bestSimilarity := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if similarity := vector.dot(centroid); similarity > bestSimilarity {
+					best, bestSimilarity = c, similarity
+				}
+			}
+			total += bestSimilarity
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		// Move each centroid to the mean of its vectors
+		for c := range centroids {
+			centroid := make(sparseVector)
+			for i, vector := range vectors {
+				if assignments[i] == c {
+					for term, weight := range vector {
+						centroid[term] += weight
+					}
+				}
+			}
+			if len(centroid) > 0 {
+				centroid.normalize()
+				centroids[c] = centroid
+			}
+		}
+	}
+	return assignments, total
+}
+
+// discoverTopics clusters videos and labels each cluster with the terms that
+// are most over-represented in it compared with all the videos. Clusters
+// smaller than minSize are dropped; the rest are returned largest first.
+func discoverTopics(videos []Video, k, minSize, terms int, seed uint64) []topicCluster {
+	vectors := tfidfVectors(videos, 2)
+	var usable []sparseVector
+	var usableVideos []Video
+	for i, vector := range vectors {
+		if vector != nil {
+			usable = append(usable, vector)
+			usableVideos = append(usableVideos, videos[i])
+		}
+	}
+	if len(usable) == 0 {
+		return nil
+	}
+	if k <= 0 {
+		k = int(math.Round(math.Sqrt(float64(len(usable)))))
+	}
+	k = max(1, min(k, len(usable)))
+
+	// k-means depends on its starting points, so keep the best of several runs
+	random := rand.New(rand.NewPCG(seed, seed))
+	var assignments []int
+	best := math.Inf(-1)
+	for run := 0; run < kMeansRuns; run++ {
+		if runAssignments, total := kMeans(usable, k, random); total > best {
+			assignments, best = runAssignments, total
+		}
+	}
+
+	// Average weight of every term over all videos, to find what is distinctive
+	overall := make(sparseVector)
+	for _, vector := range usable {
+		for term, weight := range vector {
+			overall[term] += weight / float64(len(usable))
+		}
+	}
+
+	var clusters []topicCluster
+	for c := 0; c < k; c++ {
+		var members []sparseVector
+		var cluster topicCluster
+		documentFrequency := make(map[string]int)
+		for i, vector := range usable {
+			if assignments[i] == c {
+				members = append(members, vector)
+				cluster.Videos = append(cluster.Videos, usableVideos[i])
+				for term := range vector {
+					documentFrequency[term]++
+				}
+			}
+		}
+		if len(members) < max(minSize, 1) {
+			continue
+		}
+
+		mean := make(sparseVector)
+		for _, vector := range members {
+			for term, weight := range vector {
+				mean[term] += weight / float64(len(members))
+			}
+		}
+
+		// A term must be in at least two of the cluster's videos to label it
+		var candidates []string
+		for term := range mean {
+			if documentFrequency[term] >= min(2, len(members)) {
+				candidates = append(candidates, term)
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			a, b := mean[candidates[i]]-overall[candidates[i]], mean[candidates[j]]-overall[candidates[j]]
+			if a != b {
+				return a > b
+			}
+			return candidates[i] < candidates[j]
+		})
+		cluster.Terms = candidates[:min(terms, len(candidates))]
+
+		// Cohesion is the average similarity of the videos to their centre
+		unitMean := make(sparseVector)
+		for term, weight := range mean {
+			unitMean[term] = weight
+		}
+		unitMean.normalize()
+		for _, vector := range members {
+			cluster.Cohesion += vector.dot(unitMean) / float64(len(members))
+		}
+
+		if len(cluster.Terms) > 0 {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Videos) > len(clusters[j].Videos)
+	})
+	return clusters
+}
+
+// titleCase capitalises the first letter of each word
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// suggestCategory turns a cluster into a category named after its top terms,
+// made unique against the names already taken and the reserved ones
+func suggestCategory(cluster topicCluster, taken []string) CategoryRule {
+	var nameTerms []string
+	for _, term := range cluster.Terms {
+		// Skip terms already covered by a longer or shorter one in the name
+		covered := false
+		for _, existing := range nameTerms {
+			if strings.Contains(existing, term) || strings.Contains(term, existing) {
+				covered = true
+			}
+		}
+		if !covered {
+			nameTerms = append(nameTerms, term)
+		}
+		if len(nameTerms) == 2 {
+			break
+		}
+	}
+
+	name := titleCase(strings.Join(nameTerms, " & "))
+	unique := name
+	for n := 2; containsFold(taken, unique) || unique == otherCategory || unique == excludeCategory || unique == todayPlaylist; n++ {
+		unique = fmt.Sprintf("%s %d", name, n)
+	}
+	return CategoryRule{Name: unique, Keywords: cluster.Terms}
+}
+
+// runDiscover implements the `discover` command
+func runDiscover(args []string) {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	k := flags.Int("k", 0, "number of clusters (default the square root of the number of Other videos)")
+	minSize := flags.Int("min-size", 3, "smallest cluster worth suggesting a category for")
+	terms := flags.Int("keywords", 6, "seed keywords to suggest per category")
+	seed := flags.Uint64("seed", 1, "random seed, change it to get different clusters")
+	output := flags.String("o", "suggested_rules.json", "file to write the suggested categories to, as a rules patch")
+	apply := flags.Bool("apply", false, "add the suggested categories to the rules file straight away")
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		log.Fatalf("Error reading overrides from library: %v", err)
+	}
+
+	var other []Video
+	for _, catVideos := range categorizeVideos(videos, rules, overrides) {
+		if catVideos.Category == otherCategory {
+			other = append(other, catVideos.Videos...)
+		}
+	}
+	fmt.Printf("Clustering %d videos in %s\n", len(other), otherCategory)
+
+	clusters := discoverTopics(other, *k, *minSize, *terms, *seed)
+	if len(clusters) == 0 {
+		fmt.Println("No topics found, there may be too few videos or too little in common between them")
+		return
+	}
+
+	var patch rulesPatch
+	taken := rules.categoryNames()
+	for _, cluster := range clusters {
+		category := suggestCategory(cluster, taken)
+		taken = append(taken, category.Name)
+		patch.AddCategories = append(patch.AddCategories, category)
+
+		fmt.Printf("\n%s: %d videos, cohesion %.2f\n", category.Name, len(cluster.Videos), cluster.Cohesion)
+		fmt.Printf("  Keywords: %s\n", strings.Join(category.Keywords, ", "))
+		for _, video := range cluster.Videos[:min(5, len(cluster.Videos))] {
+			fmt.Printf("  - %s\n", video.Title)
+		}
+		if len(cluster.Videos) > 5 {
+			fmt.Printf("  ... and %d more\n", len(cluster.Videos)-5)
+		}
+	}
+
+	if *apply {
+		if err := patch.apply(rules); err != nil {
+			log.Fatalf("Error applying suggestions: %v", err)
+		}
+		if err := saveRules(*rulesPath, rules); err != nil {
+			log.Fatalf("Error saving rules: %v", err)
+		}
+		fmt.Printf("\nAdded %d categories to %s\n", len(patch.AddCategories), *rulesPath)
+		return
+	}
+	if err := saveRulesPatch(*output, patch); err != nil {
+		log.Fatalf("Error saving suggestions: %v", err)
+	}
+	fmt.Printf("\nSuggestions saved to %s; rename or trim them, then apply with `go run . patch %s`\n", *output, *output)
+}
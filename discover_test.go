@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// topicVideos are two clear topics plus a video that shares nothing
+func topicVideos() []Video {
+	var videos []Video
+	for _, title := range []string{
+		"Kubernetes helm charts explained",
+		"Kubernetes helm operators",
+		"Kubernetes networking deep dive",
+		"Debugging Kubernetes networking",
+		"Sourdough bread starter",
+		"Sourdough bread shaping",
+		"Sourdough starter feeding",
+		"Baking sourdough bread at home",
+		"Roman empire history",
+	} {
+		videos = append(videos, Video{Title: title})
+	}
+	return videos
+}
+
+func TestTFIDFVectors(t *testing.T) {
+	vectors := tfidfVectors(topicVideos(), 2)
+
+	// Terms in a single video are dropped, so the last video has none
+	if vectors[8] != nil {
+		t.Errorf("video with no shared terms has vector %v", vectors[8])
+	}
+	if _, ok := vectors[0]["explained"]; ok {
+		t.Errorf("term in a single video was kept")
+	}
+	for i, vector := range vectors[:8] {
+		if norm := math.Sqrt(vector.dot(vector)); math.Abs(norm-1) > 1e-9 {
+			t.Errorf("vector %d has length %v, want 1", i, norm)
+		}
+	}
+
+	// "kubernetes helm" is in two videos and "kubernetes" in four, so the
+	// rarer term weighs more
+	if vectors[1]["kubernetes helm"] <= vectors[1]["kubernetes"] {
+		t.Errorf("rarer term weighs %v, common one %v", vectors[1]["kubernetes helm"], vectors[1]["kubernetes"])
+	}
+}
+
+func TestDiscoverTopics(t *testing.T) {
+	clusters := discoverTopics(topicVideos(), 2, 2, 3, 1)
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2", len(clusters))
+	}
+
+	byTopic := map[string]topicCluster{}
+	for _, cluster := range clusters {
+		byTopic[cluster.Terms[0]] = cluster
+	}
+	kubernetes, sourdough := byTopic["kubernetes"], byTopic["sourdough"]
+	if len(kubernetes.Videos) != 4 || len(sourdough.Videos) != 4 {
+		t.Fatalf("clusters = %+v, want four Kubernetes and four sourdough videos", clusters)
+	}
+	for _, video := range kubernetes.Videos {
+		if video.Title[:9] == "Sourdough" {
+			t.Errorf("%q is in the Kubernetes cluster", video.Title)
+		}
+	}
+	if kubernetes.Cohesion <= 0 || kubernetes.Cohesion > 1+1e-9 {
+		t.Errorf("cohesion = %v", kubernetes.Cohesion)
+	}
+
+	// The same seed gives the same clusters; cohesion is summed in map order,
+	// so it can differ in the last bit
+	again := discoverTopics(topicVideos(), 2, 2, 3, 1)
+	for i := range clusters {
+		if !reflect.DeepEqual(again[i].Terms, clusters[i].Terms) || !reflect.DeepEqual(again[i].Videos, clusters[i].Videos) ||
+			math.Abs(again[i].Cohesion-clusters[i].Cohesion) > 1e-9 {
+			t.Errorf("cluster %d changed between runs with the same seed", i)
+		}
+	}
+
+	// Clusters smaller than minSize are dropped
+	if clusters := discoverTopics(topicVideos(), 2, 5, 3, 1); len(clusters) != 0 {
+		t.Errorf("got %d clusters of at least 5 videos, want none", len(clusters))
+	}
+}
+
+func TestSuggestCategory(t *testing.T) {
+	tests := []struct {
+		terms []string
+		taken []string
+		want  string
+	}{
+		{[]string{"sourdough", "bread", "starter"}, nil, "Sourdough & Bread"},
+		// "kubernetes helm" already covers "helm"
+		{[]string{"kubernetes helm", "helm", "charts"}, nil, "Kubernetes Helm & Charts"},
+		{[]string{"sourdough", "bread"}, []string{"sourdough & bread"}, "Sourdough & Bread 2"},
+		{[]string{"other"}, nil, "Other 2"},
+		{[]string{"today"}, nil, "Today 2"},
+	}
+	for _, tt := range tests {
+		category := suggestCategory(topicCluster{Terms: tt.terms}, tt.taken)
+		if category.Name != tt.want {
+			t.Errorf("suggestCategory(%v) = %q, want %q", tt.terms, category.Name, tt.want)
+		}
+		if !reflect.DeepEqual(category.Keywords, tt.terms) {
+			t.Errorf("keywords = %v, want %v", category.Keywords, tt.terms)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// embeddingBatchSize is how many texts are sent to an endpoint per request
+const embeddingBatchSize = 64
+
+// embeddingDescriptionRunes is how much of a description is embedded with the
+// title; the start of a description says the most about the video
+const embeddingDescriptionRunes = 300
+
+// EmbeddingsConfig turns on semantic categorization in rules.json: videos and
+// categories are turned into vectors, and each video is compared with each
+// category by cosine similarity
+type EmbeddingsConfig struct {
+	// Model is a word vector file on disk in the text .vec format used by
+	// fastText and GloVe, optionally gzipped
+	Model string `json:"model,omitempty"`
+
+	// MaxWords only loads the first (most common) words of the model, to
+	// save memory and start up faster
+	MaxWords int `json:"maxWords,omitempty"`
+
+	// Endpoint is an OpenAI-compatible embeddings URL used instead of
+	// Model, e.g. http://localhost:11434/v1/embeddings for Ollama, and
+	// EndpointModel the model it should use
+	Endpoint      string `json:"endpoint,omitempty"`
+	EndpointModel string `json:"endpointModel,omitempty"`
+
+	// APIKeyEnv names the environment variable holding the endpoint's API
+	// key, if it needs one
+	APIKeyEnv string `json:"apiKeyEnv,omitempty"`
+
+	// Mode is "fallback" (the default) to only categorize videos no keyword
+	// matched, or "primary" to let the similarity overrule the keywords
+	Mode string `json:"mode,omitempty"`
+
+	// MinSimilarity is the cosine similarity the closest category needs to
+	// be used, 0.5 by default
+	MinSimilarity float64 `json:"minSimilarity,omitempty"`
+}
+
+// validate checks exactly one backend is set and the mode and similarity
+func (c *EmbeddingsConfig) validate() error {
+	switch {
+	case (c.Model == "") == (c.Endpoint == ""):
+		return fmt.Errorf("embeddings need either a model file or an endpoint")
+	case c.Mode != "" && c.Mode != "fallback" && c.Mode != "primary":
+		return fmt.Errorf("embeddings mode must be fallback or primary, not %q", c.Mode)
+	case c.MinSimilarity < 0 || c.MinSimilarity > 1:
+		return fmt.Errorf("embeddings minSimilarity must be between 0 and 1")
+	case c.MaxWords < 0:
+		return fmt.Errorf("embeddings maxWords can't be negative")
+	}
+	return nil
+}
+
+// minSimilarity returns MinSimilarity or its default
+func (c *EmbeddingsConfig) minSimilarity() float64 {
+	if c.MinSimilarity == 0 {
+		return 0.5
+	}
+	return c.MinSimilarity
+}
+
+// embedder turns texts into vectors; a nil vector means the text couldn't be
+// embedded
+type embedder interface {
+	embed(texts []string) ([][]float64, error)
+}
+
+// wordVectors is a word vector model; a text's vector is the average of its
+// words' vectors
+type wordVectors struct {
+	vectors map[string][]float32
+}
+
+// loadWordVectors reads a .vec file: one word and its vector per line, after
+// an optional "<words> <dimensions>" header line
+func loadWordVectors(filename string, maxWords int) (*wordVectors, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	model := &wordVectors{vectors: make(map[string][]float32)}
+	dimensions := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if line == 1 && len(fields) == 2 {
+			continue
+		}
+		if len(fields) < 2 {
+			continue
+		}
+		if dimensions == 0 {
+			dimensions = len(fields) - 1
+		}
+		if len(fields)-1 != dimensions {
+			return nil, fmt.Errorf("%s:%d: %d dimensions, expected %d", filename, line, len(fields)-1, dimensions)
+		}
+
+		vector := make([]float32, dimensions)
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+			}
+			vector[i] = float32(value)
+		}
+		word := strings.ToLower(fields[0])
+		if _, ok := model.vectors[word]; !ok {
+			model.vectors[word] = vector
+		}
+		if maxWords > 0 && len(model.vectors) >= maxWords {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if len(model.vectors) == 0 {
+		return nil, fmt.Errorf("%s: no word vectors", filename)
+	}
+	return model, nil
+}
+
+// embed averages the vectors of each text's words, skipping unknown words
+func (m *wordVectors) embed(texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		var average []float64
+		words := 0
+		for _, word := range tokenize(text) {
+			vector, ok := m.vectors[word]
+			if !ok {
+				continue
+			}
+			if average == nil {
+				average = make([]float64, len(vector))
+			}
+			for j, value := range vector {
+				average[j] += float64(value)
+			}
+			words++
+		}
+		for j := range average {
+			average[j] /= float64(words)
+		}
+		embeddings[i] = average
+	}
+	return embeddings, nil
+}
+
+// embeddingEndpoint calls an OpenAI-compatible /embeddings API
+type embeddingEndpoint struct {
+	url, model, apiKey string
+	client             *http.Client
+}
+
+// embeddingRequest and embeddingResponse are the endpoint's JSON bodies
+type embeddingRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embed sends the texts to the endpoint in batches
+func (e *embeddingEndpoint) embed(texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		batch := texts[start:min(start+embeddingBatchSize, len(texts))]
+		vectors, err := e.embedBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, vectors...)
+	}
+	return embeddings, nil
+}
+
+// embedBatch makes a single request to the endpoint
+func (e *embeddingEndpoint) embedBatch(texts []string) ([][]float64, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		return nil, fmt.Errorf("%s: %s: %s", e.url, resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var response embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("%s: %v", e.url, err)
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("%s: got %d embeddings for %d texts", e.url, len(response.Data), len(texts))
+	}
+	vectors := make([][]float64, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, fmt.Errorf("%s: embedding index %d out of range", e.url, data.Index)
+		}
+		vectors[data.Index] = data.Embedding
+	}
+	return vectors, nil
+}
+
+// semanticCategorizer compares videos with categories by the cosine
+// similarity of their embeddings. The backend is set up on first use, so
+// commands that don't categorize never load the model.
+type semanticCategorizer struct {
+	config   *EmbeddingsConfig
+	backend  embedder
+	cache    map[string][]float64
+	category map[string][][]float64
+}
+
+// semanticMatch is the category closest to a video
+type semanticMatch struct {
+	Category   string
+	Similarity float64
+}
+
+// newSemanticCategorizer returns a categorizer for the rules' embeddings config
+func newSemanticCategorizer(config *EmbeddingsConfig) *semanticCategorizer {
+	return &semanticCategorizer{config: config, cache: make(map[string][]float64)}
+}
+
+// categoryTexts are the texts a category is compared by: its description and
+// examples, or its name and keywords if it has neither
+func categoryTexts(category CategoryRule) []string {
+	var texts []string
+	if category.Description != "" {
+		texts = append(texts, category.Description)
+	}
+	texts = append(texts, category.Examples...)
+	if len(texts) == 0 {
+		texts = append(texts, category.Name+": "+strings.Join(category.Keywords, ", "))
+	}
+	return texts
+}
+
+// embeddingText is the part of a video that is embedded: the title and the
+// start of the description
+func embeddingText(video Video) string {
+	description := []rune(strings.TrimSpace(video.Description))
+	if len(description) > embeddingDescriptionRunes {
+		description = description[:embeddingDescriptionRunes]
+	}
+	if len(description) == 0 {
+		return video.Title
+	}
+	return video.Title + "\n" + string(description)
+}
+
+// prepare sets up the backend and embeds the categories
+func (s *semanticCategorizer) prepare(categories []CategoryRule) error {
+	if s.backend == nil {
+		if s.config.Endpoint != "" {
+			s.backend = &embeddingEndpoint{
+				url:    s.config.Endpoint,
+				model:  s.config.EndpointModel,
+				apiKey: os.Getenv(s.config.APIKeyEnv),
+				client: &http.Client{Timeout: time.Minute},
+			}
+		} else {
+			model, err := loadWordVectors(s.config.Model, s.config.MaxWords)
+			if err != nil {
+				return err
+			}
+			s.backend = model
+		}
+	}
+
+	// Every category's texts are embedded in one go
+	var texts []string
+	for _, category := range categories {
+		texts = append(texts, categoryTexts(category)...)
+	}
+	vectors, err := s.embed(texts)
+	if err != nil {
+		return err
+	}
+	s.category = make(map[string][][]float64)
+	for _, category := range categories {
+		count := len(categoryTexts(category))
+		s.category[category.Name], vectors = vectors[:count], vectors[count:]
+	}
+	return nil
+}
+
+// embed embeds texts, only sending those it hasn't seen before to the backend
+func (s *semanticCategorizer) embed(texts []string) ([][]float64, error) {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		if _, ok := s.cache[text]; !ok && !seen[text] {
+			missing = append(missing, text)
+			seen[text] = true
+		}
+	}
+	if len(missing) > 0 {
+		vectors, err := s.backend.embed(missing)
+		if err != nil {
+			return nil, err
+		}
+		for i, text := range missing {
+			s.cache[text] = vectors[i]
+		}
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = s.cache[text]
+	}
+	return vectors, nil
+}
+
+// nearest returns the closest category to each video, with an empty category
+// for videos that couldn't be embedded
+func (s *semanticCategorizer) nearest(videos []Video, categories []CategoryRule) ([]semanticMatch, error) {
+	if err := s.prepare(categories); err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(videos))
+	for i, video := range videos {
+		texts[i] = embeddingText(video)
+	}
+	vectors, err := s.embed(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]semanticMatch, len(videos))
+	for i, vector := range vectors {
+		for _, category := range categories {
+			for _, categoryVector := range s.category[category.Name] {
+				if similarity := cosineSimilarity(vector, categoryVector); similarity > matches[i].Similarity {
+					matches[i] = semanticMatch{Category: category.Name, Similarity: similarity}
+				}
+			}
+		}
+	}
+	return matches, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or 0
+// if either is missing or they have different dimensions
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"same direction", []float64{1, 2}, []float64{2, 4}, 1},
+		{"orthogonal", []float64{1, 0}, []float64{0, 3}, 0},
+		{"opposite", []float64{1, 1}, []float64{-1, -1}, -1},
+		{"zero vector", []float64{0, 0}, []float64{1, 1}, 0},
+		{"missing", nil, []float64{1, 1}, 0},
+		{"different dimensions", []float64{1, 1}, []float64{1, 1, 1}, 0},
+	}
+	for _, tt := range tests {
+		if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: cosineSimilarity = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddingsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EmbeddingsConfig
+		valid  bool
+	}{
+		{"model", EmbeddingsConfig{Model: "vectors.vec"}, true},
+		{"endpoint", EmbeddingsConfig{Endpoint: "http://localhost:11434/v1/embeddings", Mode: "primary", MinSimilarity: 0.7}, true},
+		{"both backends", EmbeddingsConfig{Model: "vectors.vec", Endpoint: "http://localhost:11434/v1/embeddings"}, false},
+		{"neither backend", EmbeddingsConfig{Mode: "fallback"}, false},
+		{"bad mode", EmbeddingsConfig{Model: "vectors.vec", Mode: "secondary"}, false},
+		{"similarity above 1", EmbeddingsConfig{Model: "vectors.vec", MinSimilarity: 1.5}, false},
+		{"negative similarity", EmbeddingsConfig{Model: "vectors.vec", MinSimilarity: -0.1}, false},
+		{"negative max words", EmbeddingsConfig{Model: "vectors.vec", MaxWords: -1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.config.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestLoadWordVectors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+
+	// The header is skipped, words are lowercased and the first of two
+	// spellings wins
+	withHeader := write("header.vec", "3 2\nhelm 1 0\nHelm 9 9\nkubernetes 0.5 0.5\n")
+	model, err := loadWordVectors(withHeader, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]float32{"helm": {1, 0}, "kubernetes": {0.5, 0.5}}
+	if !reflect.DeepEqual(model.vectors, want) {
+		t.Errorf("with a header: vectors = %v, want %v", model.vectors, want)
+	}
+
+	noHeader := write("plain.vec", "helm 1 0 0\nkubernetes 0 1 0\nbread 0 0 1\n")
+	if model, err := loadWordVectors(noHeader, 0); err != nil || len(model.vectors) != 3 {
+		t.Errorf("without a header: got %v, %v; want 3 words", model, err)
+	}
+	if model, err := loadWordVectors(noHeader, 2); err != nil || len(model.vectors) != 2 || model.vectors["bread"] != nil {
+		t.Errorf("with maxWords 2: got %v, %v; want helm and kubernetes", model, err)
+	}
+
+	mismatch := write("mismatch.vec", "helm 1 0\nkubernetes 0 1 0\n")
+	if _, err := loadWordVectors(mismatch, 0); err == nil || !strings.Contains(err.Error(), "mismatch.vec:2") {
+		t.Errorf("dimension mismatch: err = %v, want one for line 2", err)
+	}
+	if _, err := loadWordVectors(write("empty.vec", "2 300\n"), 0); err == nil {
+		t.Errorf("a file with no vectors was accepted")
+	}
+
+	gzipped := filepath.Join(dir, "vectors.vec.gz")
+	file, err := os.Create(gzipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("2 2\nhelm 1 0\nbread 0 1\n"))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	model, err = loadWordVectors(gzipped, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A text's vector is the average of its known words
+	vectors, _ := model.embed([]string{"Helm and bread", "nothing known"})
+	if !reflect.DeepEqual(vectors, [][]float64{{0.5, 0.5}, nil}) {
+		t.Errorf("embed = %v, want [[0.5 0.5] []]", vectors)
+	}
+}
+
+func TestEmbeddingEndpoint(t *testing.T) {
+	var requests []embeddingRequest
+	respond := func(w http.ResponseWriter, request embeddingRequest) {
+		// The embeddings come back in reverse order, each holding its index
+		var response embeddingResponse
+		response.Data = make([]struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}, len(request.Input))
+		for i := range request.Input {
+			n := len(request.Input) - 1 - i
+			response.Data[i].Index = n
+			response.Data[i].Embedding = []float64{float64(len(request.Input[n]))}
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request embeddingRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			http.Error(w, "bad API key", http.StatusUnauthorized)
+		case request.Input[0] == "short":
+			json.NewEncoder(w).Encode(embeddingResponse{})
+		default:
+			respond(w, request)
+		}
+	}))
+	defer server.Close()
+	endpoint := &embeddingEndpoint{url: server.URL, model: "nomic-embed-text", apiKey: "secret", client: server.Client()}
+
+	vectors, err := endpoint.embedBatch([]string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vectors, [][]float64{{1}, {2}, {3}}) {
+		t.Errorf("embedBatch = %v, want the vectors back in input order", vectors)
+	}
+	if requests[0].Model != "nomic-embed-text" {
+		t.Errorf("model = %q, want nomic-embed-text", requests[0].Model)
+	}
+
+	if _, err := endpoint.embedBatch([]string{"short", "answer"}); err == nil || !strings.Contains(err.Error(), "got 0 embeddings for 2 texts") {
+		t.Errorf("count mismatch: err = %v", err)
+	}
+
+	unauthorized := &embeddingEndpoint{url: server.URL, client: server.Client()}
+	if _, err := unauthorized.embedBatch([]string{"a"}); err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "bad API key") {
+		t.Errorf("non-200 status: err = %v, want the status and message", err)
+	}
+
+	// embed splits the texts into batches
+	requests = nil
+	texts := make([]string, embeddingBatchSize+1)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	vectors, err = endpoint.embed(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(vectors) != len(texts) || vectors[embeddingBatchSize][0] != float64(embeddingBatchSize+1) {
+		t.Errorf("embed made %d requests for %d vectors, want 2 for %d", len(requests), len(vectors), len(texts))
+	}
+}
+
+// fakeEmbedder embeds a text as counts of its Kubernetes and cooking words,
+// and records the texts it is sent
+type fakeEmbedder struct {
+	sent []string
+}
+
+func (f *fakeEmbedder) embed(texts []string) ([][]float64, error) {
+	f.sent = append(f.sent, texts...)
+	axes := map[string]int{"kubernetes": 0, "cluster": 0, "pod": 0, "cooking": 1, "recipe": 1, "bread": 1}
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		for _, word := range tokenize(text) {
+			if axis, ok := axes[strings.TrimSuffix(word, "s")]; ok {
+				if vectors[i] == nil {
+					vectors[i] = make([]float64, 2)
+				}
+				vectors[i][axis]++
+			}
+		}
+	}
+	return vectors, nil
+}
+
+// semanticRules returns rules with a fake embedder in the given mode
+func semanticRules(t *testing.T, mode string) (*Rules, *fakeEmbedder) {
+	rules := &Rules{
+		Categories: []CategoryRule{
+			{Name: "Kubernetes", Keywords: []string{"helm"}, Description: "Kubernetes clusters"},
+			{Name: "Cooking", Keywords: []string{"sourdough"}, Examples: []string{"Bread recipes"}},
+		},
+		Embeddings: &EmbeddingsConfig{Model: "vectors.vec", Mode: mode, MinSimilarity: 0.8},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeEmbedder{}
+	rules.semantic = newSemanticCategorizer(rules.Embeddings)
+	rules.semantic.backend = fake
+	return rules, fake
+}
+
+func TestSemanticNearest(t *testing.T) {
+	rules, fake := semanticRules(t, "")
+	videos := []Video{
+		testVideo("aaaaaaaaaaa", "Pod networking"),
+		testVideo("bbbbbbbbbbb", "Helm recipe with bread"),
+		testVideo("ccccccccccc", "My holiday"),
+		testVideo("ddddddddddd", "A cluster recipe"),
+	}
+
+	matches, err := rules.semantic.nearest(videos, rules.Categories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []semanticMatch{{"Kubernetes", 1}, {"Cooking", 1}, {}, {"Kubernetes", math.Sqrt(0.5)}}
+	for i := range want {
+		if matches[i].Category != want[i].Category || math.Abs(matches[i].Similarity-want[i].Similarity) > 1e-9 {
+			t.Errorf("%q: nearest = %+v, want %+v", videos[i].Title, matches[i], want[i])
+		}
+	}
+
+	// Texts already embedded aren't sent again
+	sent := len(fake.sent)
+	if _, err := rules.semantic.nearest(videos, rules.Categories); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.sent) != sent {
+		t.Errorf("a second run sent %q again", fake.sent[sent:])
+	}
+}
+
+func TestCategorizeVideosWithEmbeddings(t *testing.T) {
+	videos := []Video{
+		testVideo("aaaaaaaaaaa", "Pod networking"),
+		testVideo("bbbbbbbbbbb", "Helm recipe with bread"),
+		testVideo("ccccccccccc", "A cluster recipe"),
+	}
+
+	// A fallback only fills in for the keywords; primary overrules them. The
+	// cluster recipe isn't similar enough to either category.
+	tests := []struct {
+		mode string
+		want map[string]string
+	}{
+		{"fallback", map[string]string{"Pod networking": "Kubernetes", "Helm recipe with bread": "Kubernetes", "A cluster recipe": otherCategory}},
+		{"primary", map[string]string{"Pod networking": "Kubernetes", "Helm recipe with bread": "Cooking", "A cluster recipe": otherCategory}},
+	}
+	for _, tt := range tests {
+		rules, _ := semanticRules(t, tt.mode)
+		got := make(map[string]string)
+		for _, catVideos := range categorizeVideos(videos, rules, nil) {
+			for _, video := range catVideos.Videos {
+				got[video.Title] = catVideos.Category
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: categories = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
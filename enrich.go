@@ -0,0 +1,233 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// videosListBatchSize is the most IDs Videos.List accepts in one call
+const videosListBatchSize = 50
+
+// isoDurationPattern matches ISO-8601 durations as returned by the API, e.g. PT1H2M3S or P1DT2H
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// videoMetadata is what the YouTube Data API returns for a video (snippet,
+// contentDetails, statistics and status). It is cached as JSON in the library.
+type videoMetadata struct {
+	Description          string   `json:"description,omitempty"`
+	Tags                 []string `json:"tags,omitempty"`
+	ChannelID            string   `json:"channelId,omitempty"`
+	ChannelTitle         string   `json:"channelTitle,omitempty"`
+	CategoryID           string   `json:"categoryId,omitempty"`
+	Duration             string   `json:"duration,omitempty"`
+	PublishedAt          string   `json:"publishedAt,omitempty"`
+	LiveBroadcastContent string   `json:"liveBroadcastContent,omitempty"`
+	DefaultLanguage      string   `json:"defaultLanguage,omitempty"`
+	DefaultAudioLanguage string   `json:"defaultAudioLanguage,omitempty"`
+	ViewCount            int64    `json:"viewCount,omitempty"`
+	LikeCount            int64    `json:"likeCount,omitempty"`
+	CommentCount         int64    `json:"commentCount,omitempty"`
+	PrivacyStatus        string   `json:"privacyStatus,omitempty"`
+	UploadStatus         string   `json:"uploadStatus,omitempty"`
+	RegionAllowed        []string `json:"regionAllowed,omitempty"`
+	RegionBlocked        []string `json:"regionBlocked,omitempty"`
+
+	// NotFound is cached for IDs Videos.List didn't return, which means the
+	// video was deleted or made private
+	NotFound bool `json:"notFound,omitempty"`
+}
+
+// metadataFromAPI converts a Videos.List item into videoMetadata
+func metadataFromAPI(item *youtube.Video) videoMetadata {
+	var meta videoMetadata
+	if s := item.Snippet; s != nil {
+		meta.Description = s.Description
+		meta.Tags = s.Tags
+		meta.ChannelID = s.ChannelId
+		meta.ChannelTitle = s.ChannelTitle
+		meta.CategoryID = s.CategoryId
+		meta.PublishedAt = s.PublishedAt
+		meta.LiveBroadcastContent = s.LiveBroadcastContent
+		meta.DefaultLanguage = s.DefaultLanguage
+		meta.DefaultAudioLanguage = s.DefaultAudioLanguage
+	}
+	if c := item.ContentDetails; c != nil {
+		meta.Duration = c.Duration
+		if r := c.RegionRestriction; r != nil {
+			meta.RegionAllowed = r.Allowed
+			meta.RegionBlocked = r.Blocked
+		}
+	}
+	if s := item.Statistics; s != nil {
+		meta.ViewCount = int64(s.ViewCount)
+		meta.LikeCount = int64(s.LikeCount)
+		meta.CommentCount = int64(s.CommentCount)
+	}
+	if s := item.Status; s != nil {
+		meta.PrivacyStatus = s.PrivacyStatus
+		meta.UploadStatus = s.UploadStatus
+	}
+	return meta
+}
+
+// applyMetadata copies API metadata onto a video. The API is more reliable
+// than the ariaLabel so its channel, views and duration win.
+func applyMetadata(video *Video, meta videoMetadata) {
+	video.Description = meta.Description
+	video.Tags = meta.Tags
+	video.ChannelID = meta.ChannelID
+	video.CategoryID = meta.CategoryID
+	video.Duration = meta.Duration
+	video.PublishedAt = meta.PublishedAt
+	video.LiveBroadcastContent = meta.LiveBroadcastContent
+	video.DefaultLanguage = meta.DefaultLanguage
+	video.DefaultAudioLanguage = meta.DefaultAudioLanguage
+	video.PrivacyStatus = meta.PrivacyStatus
+	video.UploadStatus = meta.UploadStatus
+	video.RegionAllowed = meta.RegionAllowed
+	video.RegionBlocked = meta.RegionBlocked
+	video.NotFound = meta.NotFound
+
+	if meta.ChannelTitle != "" {
+		video.Channel = meta.ChannelTitle
+	}
+	if meta.ViewCount > 0 {
+		video.Views = meta.ViewCount
+	}
+	if seconds := parseISODuration(meta.Duration); seconds > 0 {
+		video.DurationSeconds = seconds
+	}
+}
+
+// parseISODuration converts an ISO-8601 duration such as PT1H2M3S into seconds
+func parseISODuration(duration string) int {
+	match := isoDurationPattern.FindStringSubmatch(duration)
+	if match == nil {
+		return 0
+	}
+	seconds := 0
+	for i, unit := range []int{86400, 3600, 60, 1} {
+		if n, err := strconv.Atoi(match[i+1]); err == nil {
+			seconds += n * unit
+		}
+	}
+	return seconds
+}
+
+// saveMetadata caches a video's API metadata in the library
+func (l *Library) saveMetadata(videoID string, meta videoMetadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	_, err = l.db.Exec("INSERT OR REPLACE INTO metadata (video_id, data, fetched_at) VALUES (?, ?, ?)", videoID, string(data), now())
+	return err
+}
+
+// staleMetadata returns the IDs of videos in Watch Later with no cached
+// metadata, or metadata fetched before the given time
+func (l *Library) staleMetadata(before time.Time) ([]string, error) {
+	rows, err := l.db.Query(`SELECT v.id FROM videos v LEFT JOIN metadata m ON m.video_id = v.id
+		WHERE v.in_watch_later = 1 AND v.id NOT LIKE 'title:%' AND (m.fetched_at IS NULL OR m.fetched_at < ?)
+		ORDER BY v.position`, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// decodeMetadata applies cached metadata JSON (if any) to a video
+func decodeMetadata(video *Video, data sql.NullString) error {
+	if !data.Valid {
+		return nil
+	}
+	var meta videoMetadata
+	if err := json.Unmarshal([]byte(data.String), &meta); err != nil {
+		return fmt.Errorf("bad metadata for %s: %v", video.ID, err)
+	}
+	applyMetadata(video, meta)
+	return nil
+}
+
+// enrichVideos fetches metadata for every Watch Later video that has none
+// cached (or whose cache is older than maxAge) in batches of 50 IDs, so each
+// call to Videos.List costs a single quota unit. It returns the number of
+// videos fetched.
+func enrichVideos(service *youtube.Service, lib *Library, maxAge time.Duration) (int, error) {
+	ids, err := lib.staleMetadata(time.Now().Add(-maxAge))
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		fmt.Println("Video metadata is up to date")
+		return 0, nil
+	}
+
+	fetched := 0
+	for start := 0; start < len(ids); start += videosListBatchSize {
+		batch := ids[start:min(start+videosListBatchSize, len(ids))]
+		fmt.Printf("Fetching metadata for videos %d-%d of %d\n", start+1, start+len(batch), len(ids))
+
+		response, err := service.Videos.List([]string{"snippet", "contentDetails", "statistics", "status"}).Id(batch...).MaxResults(videosListBatchSize).Do()
+		if err != nil {
+			return fetched, fmt.Errorf("error listing videos: %v", err)
+		}
+
+		returned := make(map[string]bool)
+		for _, item := range response.Items {
+			if err := lib.saveMetadata(item.Id, metadataFromAPI(item)); err != nil {
+				return fetched, err
+			}
+			returned[item.Id] = true
+			fetched++
+		}
+
+		// Deleted and private videos are simply missing from the response
+		for _, id := range batch {
+			if !returned[id] {
+				if err := lib.saveMetadata(id, videoMetadata{NotFound: true}); err != nil {
+					return fetched, err
+				}
+			}
+		}
+	}
+	return fetched, nil
+}
+
+// runEnrich implements the `enrich` command
+func runEnrich(args []string) {
+	flags := flag.NewFlagSet("enrich", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	maxAge := flags.Duration("max-age", 30*24*time.Hour, "re-fetch metadata cached longer ago than this")
+	flags.Parse(args)
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	fetched, err := enrichVideos(newYouTubeService(), lib, *maxAge)
+	if err != nil {
+		log.Fatalf("Error enriching videos: %v", err)
+	}
+	fmt.Printf("Fetched metadata for %d videos\n", fetched)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		duration string
+		want     int
+	}{
+		{"PT1H2M3S", 3723},
+		{"PT15M", 900},
+		{"PT45S", 45},
+		{"PT2H", 7200},
+		{"P1DT2H", 93600},
+		{"P1DT0H0M1S", 86401},
+		{"PT0S", 0},
+		{"P0D", 0},
+		{"", 0},
+		{"1H2M", 0},
+		{"PT1H2M3", 0},
+		{"PTXM", 0},
+		{"P1W", 0},
+	}
+	for _, tt := range tests {
+		if got := parseISODuration(tt.duration); got != tt.want {
+			t.Errorf("parseISODuration(%q) = %d, want %d", tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataFromAPI(t *testing.T) {
+	item := &youtube.Video{
+		Snippet: &youtube.VideoSnippet{
+			Description:          "All about Helm",
+			Tags:                 []string{"helm", "kubernetes"},
+			ChannelId:            "UC123",
+			ChannelTitle:         "TechWorld with Nana",
+			CategoryId:           "28",
+			DefaultAudioLanguage: "en-US",
+		},
+		ContentDetails: &youtube.VideoContentDetails{
+			Duration:          "PT1H2M3S",
+			RegionRestriction: &youtube.VideoContentDetailsRegionRestriction{Blocked: []string{"DE"}},
+		},
+		Statistics: &youtube.VideoStatistics{ViewCount: 1200},
+		Status:     &youtube.VideoStatus{PrivacyStatus: "public", UploadStatus: "processed"},
+	}
+
+	meta := metadataFromAPI(item)
+	if meta.ChannelTitle != "TechWorld with Nana" || meta.Duration != "PT1H2M3S" || meta.ViewCount != 1200 ||
+		len(meta.RegionBlocked) != 1 || meta.PrivacyStatus != "public" || meta.DefaultAudioLanguage != "en-US" {
+		t.Errorf("metadata = %+v", meta)
+	}
+
+	video := Video{Channel: "from the label", Views: 10, DurationSeconds: 60}
+	applyMetadata(&video, meta)
+	if video.Channel != "TechWorld with Nana" || video.Views != 1200 || video.DurationSeconds != 3723 || video.Description != "All about Helm" {
+		t.Errorf("video = %+v", video)
+	}
+
+	// Missing parts leave the label's values alone
+	video = Video{Channel: "from the label", Views: 10, DurationSeconds: 60}
+	applyMetadata(&video, metadataFromAPI(&youtube.Video{}))
+	if video.Channel != "from the label" || video.Views != 10 || video.DurationSeconds != 60 {
+		t.Errorf("empty metadata changed the video: %+v", video)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// labelledVideo is a video from the fixture with the category it should get
+type labelledVideo struct {
+	Video    Video
+	Expected string
+}
+
+// evalResult is how a set of rules did against the labelled videos
+type evalResult struct {
+	Predicted []string
+	Correct   int
+
+	// Confusion counts videos by expected then predicted category
+	Confusion map[string]map[string]int
+}
+
+// loadLabelledVideos reads a labelled fixture, which is a
+// categorized_videos.json (any version) whose categories have been checked
+// by hand. Fields the ariaLabel provides are filled in if they are missing.
+func loadLabelledVideos(filename string) ([]labelledVideo, error) {
+	file, err := readCategorizedVideos(filename)
+	if err != nil {
+		return nil, err
+	}
+	var labelled []labelledVideo
+	for _, entry := range file.Videos {
+		video := entry.Video
+		if video.Channel == "" && video.AriaLabel != "" {
+			parseAriaLabel(&video)
+		}
+		video.Override = ""
+		labelled = append(labelled, labelledVideo{Video: video, Expected: entry.Category})
+	}
+	if len(labelled) == 0 {
+		return nil, fmt.Errorf("%s has no videos", filename)
+	}
+	return labelled, nil
+}
+
+// evaluate categorizes each labelled video on its own, without overrides, and
+// compares the result with its label
+func evaluate(labelled []labelledVideo, rules *Rules) evalResult {
+	result := evalResult{Confusion: make(map[string]map[string]int)}
+	for _, item := range labelled {
+		predicted := otherCategory
+		for _, catVideos := range categorizeVideos([]Video{item.Video}, rules, nil) {
+			if len(catVideos.Videos) > 0 {
+				predicted = catVideos.Category
+			}
+		}
+
+		result.Predicted = append(result.Predicted, predicted)
+		if predicted == item.Expected {
+			result.Correct++
+		}
+		if result.Confusion[item.Expected] == nil {
+			result.Confusion[item.Expected] = make(map[string]int)
+		}
+		result.Confusion[item.Expected][predicted]++
+	}
+	return result
+}
+
+// categoryScore is how well one category was predicted
+type categoryScore struct {
+	Expected, Predicted   int
+	Precision, Recall, F1 float64
+}
+
+// score works out a category's precision, recall and F1 from the confusion
+// matrix; all three are 0 when nothing was expected or predicted
+func (r evalResult) score(category string) categoryScore {
+	truePositives := r.Confusion[category][category]
+	var score categoryScore
+	for _, count := range r.Confusion[category] {
+		score.Expected += count
+	}
+	for _, row := range r.Confusion {
+		score.Predicted += row[category]
+	}
+
+	score.Precision, score.Recall = ratio(truePositives, score.Predicted), ratio(truePositives, score.Expected)
+	if score.Precision+score.Recall > 0 {
+		score.F1 = 2 * score.Precision * score.Recall / (score.Precision + score.Recall)
+	}
+	return score
+}
+
+// evalCategories lists every category in rules order, then Other, then any
+// labels the rules don't know about
+func evalCategories(rules *Rules, labelled []labelledVideo) []string {
+	categories := append(rules.categoryNames(), otherCategory)
+	for _, item := range labelled {
+		if !containsString(categories, item.Expected) {
+			categories = append(categories, item.Expected)
+		}
+	}
+	return categories
+}
+
+// printEval prints accuracy, per-category precision and recall and the
+// confusion matrix
+func printEval(result evalResult, labelled []labelledVideo, categories []string) {
+	fmt.Printf("Accuracy: %.1f%% (%d of %d videos)\n\n", 100*float64(result.Correct)/float64(len(labelled)), result.Correct, len(labelled))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "#\tCategory\tVideos\tPrecision\tRecall\tF1\t")
+	for i, category := range categories {
+		score := result.score(category)
+		if score.Expected == 0 && score.Predicted == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.2f\t%.2f\t%.2f\t\n", i+1, category, score.Expected, score.Precision, score.Recall, score.F1)
+	}
+	w.Flush()
+
+	// Rows are the expected category, columns the predicted one, by number
+	fmt.Println("\nConfusion matrix (rows expected, columns predicted):")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.AlignRight)
+	header := []string{""}
+	for i := range categories {
+		header = append(header, fmt.Sprint(i+1))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t")+"\t")
+	for i, expected := range categories {
+		row := []string{fmt.Sprint(i + 1)}
+		for _, predicted := range categories {
+			if n := result.Confusion[expected][predicted]; n > 0 {
+				row = append(row, fmt.Sprint(n))
+			} else {
+				row = append(row, ".")
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t")+"\t")
+	}
+	w.Flush()
+}
+
+// ratio returns a/b, or 0 when b is 0
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// runEval implements the `eval` command
+func runEval(args []string) {
+	flags := flag.NewFlagSet("eval", flag.ExitOnError)
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights to evaluate")
+	labelsPath := flags.String("labels", "testdata/labelled_videos.json", "labelled videos, in the categorized_videos.json format")
+	baselinePath := flags.String("baseline", "", "previous rules file to compare with, e.g. from `git show HEAD~1:rules.json`")
+	showMistakes := flags.Bool("mistakes", false, "list every video categorized wrongly")
+	failOnRegression := flags.Bool("fail-on-regression", false, "exit with status 1 if any video the baseline got right is now wrong")
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+	labelled, err := loadLabelledVideos(*labelsPath)
+	if err != nil {
+		log.Fatalf("Error loading labelled videos: %v", err)
+	}
+
+	result := evaluate(labelled, rules)
+	printEval(result, labelled, evalCategories(rules, labelled))
+
+	if *showMistakes {
+		fmt.Println("\nMistakes:")
+		for i, item := range labelled {
+			if result.Predicted[i] != item.Expected {
+				fmt.Printf("  %s: expected %s, got %s\n", item.Video.Title, item.Expected, result.Predicted[i])
+			}
+		}
+	}
+
+	if *baselinePath == "" {
+		return
+	}
+	baselineRules, err := loadRules(*baselinePath)
+	if err != nil {
+		log.Fatalf("Error loading baseline rules: %v", err)
+	}
+	baseline := evaluate(labelled, baselineRules)
+
+	var regressions, fixes []string
+	for i, item := range labelled {
+		wasRight, isRight := baseline.Predicted[i] == item.Expected, result.Predicted[i] == item.Expected
+		switch {
+		case wasRight && !isRight:
+			regressions = append(regressions, fmt.Sprintf("  %s: was %s, now %s", item.Video.Title, baseline.Predicted[i], result.Predicted[i]))
+		case !wasRight && isRight:
+			fixes = append(fixes, fmt.Sprintf("  %s: was %s, now %s", item.Video.Title, baseline.Predicted[i], result.Predicted[i]))
+		}
+	}
+
+	fmt.Printf("\nBaseline %s: %.1f%%, now %.1f%%\n", *baselinePath,
+		100*float64(baseline.Correct)/float64(len(labelled)), 100*float64(result.Correct)/float64(len(labelled)))
+	fmt.Printf("Regressions: %d\n", len(regressions))
+	for _, line := range regressions {
+		fmt.Println(line)
+	}
+	fmt.Printf("Fixes: %d\n", len(fixes))
+	for _, line := range fixes {
+		fmt.Println(line)
+	}
+	if *failOnRegression && len(regressions) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	rules := &Rules{
+		Fields: map[string]float64{"title": 1},
+		Categories: []CategoryRule{
+			{Name: "Kubernetes", Keywords: []string{"kubernetes", "helm"}},
+			{Name: "Databases", Keywords: []string{"sql"}},
+			{Name: "Security", Keywords: []string{"owasp"}},
+		},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	labelled := []labelledVideo{
+		{Video{Title: "Helm charts"}, "Kubernetes"},
+		{Video{Title: "Kubernetes operators"}, "Kubernetes"},
+		{Video{Title: "Kubernetes and SQL on a budget"}, "Databases"},
+		{Video{Title: "SQL joins"}, "Databases"},
+		{Video{Title: "Postgres indexes"}, "Databases"},
+		{Video{Title: "Password cracking"}, "Security"},
+		{Video{Title: "Sourdough bread"}, otherCategory},
+	}
+
+	result := evaluate(labelled, rules)
+	wantPredicted := []string{"Kubernetes", "Kubernetes", "Kubernetes", "Databases", otherCategory, otherCategory, otherCategory}
+	for i, want := range wantPredicted {
+		if result.Predicted[i] != want {
+			t.Errorf("%q predicted %q, want %q", labelled[i].Video.Title, result.Predicted[i], want)
+		}
+	}
+	if result.Correct != 4 {
+		t.Errorf("correct = %d, want 4", result.Correct)
+	}
+
+	tests := []struct {
+		category              string
+		expected, predicted   int
+		precision, recall, f1 float64
+	}{
+		{"Kubernetes", 2, 3, 2.0 / 3, 1, 0.8},
+		{"Databases", 3, 1, 1, 1.0 / 3, 0.5},
+		// Expected but never predicted: nothing to be precise about
+		{"Security", 1, 0, 0, 0, 0},
+		{otherCategory, 1, 3, 1.0 / 3, 1, 0.5},
+		// Neither expected nor predicted
+		{"Linux", 0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		score := result.score(tt.category)
+		if score.Expected != tt.expected || score.Predicted != tt.predicted {
+			t.Errorf("%s: %d expected and %d predicted, want %d and %d", tt.category, score.Expected, score.Predicted, tt.expected, tt.predicted)
+		}
+		for _, got := range []struct {
+			name      string
+			got, want float64
+		}{{"precision", score.Precision, tt.precision}, {"recall", score.Recall, tt.recall}, {"F1", score.F1, tt.f1}} {
+			if math.Abs(got.got-got.want) > 1e-9 {
+				t.Errorf("%s: %s = %v, want %v", tt.category, got.name, got.got, got.want)
+			}
+		}
+	}
+}
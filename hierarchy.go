@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Placeholders in the playlistTitle template
+const (
+	namePlaceholder = "{name}"
+	pathPlaceholder = "{path}"
+)
+
+// defaultPlaylistTitle is used when rules.json doesn't set playlistTitle
+const defaultPlaylistTitle = namePlaceholder + " Playlist"
+
+// categoryPathSeparator joins a category to its parents in {path} and reports
+const categoryPathSeparator = " / "
+
+// validateHierarchy checks every parent exists and no category is its own ancestor
+func (r *Rules) validateHierarchy() error {
+	names := r.categoryNames()
+	for _, category := range r.Categories {
+		if category.Parent != "" && !containsString(names, category.Parent) {
+			return fmt.Errorf("category %q: unknown parent %q", category.Name, category.Parent)
+		}
+		seen := map[string]bool{category.Name: true}
+		for parent := category.Parent; parent != ""; parent = r.parentOf(parent) {
+			if seen[parent] {
+				return fmt.Errorf("category %q is its own ancestor", category.Name)
+			}
+			seen[parent] = true
+		}
+	}
+	if r.PlaylistTitle != "" && !strings.Contains(r.PlaylistTitle, namePlaceholder) && !strings.Contains(r.PlaylistTitle, pathPlaceholder) {
+		return fmt.Errorf("playlistTitle must contain %s or %s", namePlaceholder, pathPlaceholder)
+	}
+	return nil
+}
+
+// parentOf returns a category's parent, or "" for a top-level or unknown category
+func (r *Rules) parentOf(name string) string {
+	for _, category := range r.Categories {
+		if category.Name == name {
+			return category.Parent
+		}
+	}
+	return ""
+}
+
+// parents maps every category with a parent to it
+func (r *Rules) parents() map[string]string {
+	parents := make(map[string]string)
+	for _, category := range r.Categories {
+		if category.Parent != "" {
+			parents[category.Name] = category.Parent
+		}
+	}
+	return parents
+}
+
+// children returns a category's direct children in rules order; "" gives the
+// top-level categories
+func (r *Rules) children(name string) []string {
+	var children []string
+	for _, category := range r.Categories {
+		if category.Parent == name {
+			children = append(children, category.Name)
+		}
+	}
+	return children
+}
+
+// withDescendants returns the categories plus all their children,
+// grandchildren and so on
+func (r *Rules) withDescendants(names []string) []string {
+	all := append([]string{}, names...)
+	for i := 0; i < len(all); i++ {
+		for _, child := range r.children(all[i]) {
+			if !containsString(all, child) {
+				all = append(all, child)
+			}
+		}
+	}
+	return all
+}
+
+// categoryPath returns the category's name after those of its ancestors,
+// e.g. "Cloud / AWS"
+func categoryPath(parents map[string]string, name string) string {
+	path := name
+	for parent := parents[name]; parent != ""; parent = parents[parent] {
+		path = parent + categoryPathSeparator + path
+	}
+	return path
+}
+
+// playlistTitle returns the title of a category's playlist from the
+// playlistTitle template
+func (r *Rules) playlistTitle(name string) string {
+	template := r.PlaylistTitle
+	if template == "" {
+		template = defaultPlaylistTitle
+	}
+	return strings.NewReplacer(namePlaceholder, name, pathPlaceholder, categoryPath(r.parents(), name)).Replace(template)
+}
+
+// pickCategory returns the category a video's scores put it in: the best
+// matching top-level branch, then within it the most specific matching child,
+// or Other if nothing matched. A branch scores as well as its best category,
+// and ties go to the category listed first.
+func (r *Rules) pickCategory(scores map[string]float64) string {
+	// Best score of each category and everything under it
+	var branchScore func(name string) float64
+	branchScore = func(name string) float64 {
+		best := scores[name]
+		for _, child := range r.children(name) {
+			best = max(best, branchScore(child))
+		}
+		return best
+	}
+
+	picked := ""
+	for {
+		next, nextScore := "", 0.0
+		for _, child := range r.children(picked) {
+			if score := branchScore(child); score > nextScore {
+				next, nextScore = child, score
+			}
+		}
+		if next == "" {
+			break
+		}
+		picked = next
+	}
+	if picked == "" {
+		return otherCategory
+	}
+	return picked
+}
+
+// hasChildren reports whether any category has name as its parent
+func hasChildren(parents map[string]string, name string) bool {
+	for _, parent := range parents {
+		if parent == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rollUp adds each category's videos to every ancestor's, for reports that
+// show a parent's total including its children
+func rollUp(parents map[string]string, categorizedVideos []CategorizedVideos) map[string][]Video {
+	rolled := make(map[string][]Video)
+	for _, catVideos := range categorizedVideos {
+		for name := catVideos.Category; name != ""; name = parents[name] {
+			rolled[name] = append(rolled[name], catVideos.Videos...)
+		}
+	}
+	return rolled
+}
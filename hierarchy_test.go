@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+// hierarchyRules nests AWS and Azure under Cloud and Lambda under AWS
+func hierarchyRules(t *testing.T) *Rules {
+	t.Helper()
+	rules := &Rules{Categories: []CategoryRule{
+		{Name: "Cloud"},
+		{Name: "AWS", Parent: "Cloud"},
+		{Name: "Lambda", Parent: "AWS"},
+		{Name: "Azure", Parent: "Cloud"},
+		{Name: "Databases"},
+	}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestPickCategory(t *testing.T) {
+	rules := hierarchyRules(t)
+	tests := []struct {
+		name   string
+		scores map[string]float64
+		want   string
+	}{
+		{"nothing matched", map[string]float64{}, otherCategory},
+		{"parent only", map[string]float64{"Cloud": 1}, "Cloud"},
+		{"most specific child", map[string]float64{"Cloud": 1, "AWS": 0.4, "Lambda": 0.4}, "Lambda"},
+		{"child without its parent", map[string]float64{"Lambda": 0.4}, "Lambda"},
+		{"best branch wins", map[string]float64{"Databases": 1, "Lambda": 1.4}, "Lambda"},
+		{"branch scores as its best category", map[string]float64{"Cloud": 0.2, "Azure": 0.2, "Databases": 1}, "Databases"},
+		{"better child", map[string]float64{"AWS": 0.4, "Azure": 1}, "Azure"},
+		{"ties go to the first listed", map[string]float64{"AWS": 1, "Azure": 1, "Databases": 1}, "AWS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.pickCategory(tt.scores); got != tt.want {
+				t.Errorf("pickCategory(%v) = %q, want %q", tt.scores, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollUp(t *testing.T) {
+	rules := hierarchyRules(t)
+	lambda := testVideo("aaaaaaaaaaa", "Lambda cold starts")
+	aws := testVideo("bbbbbbbbbbb", "AWS networking")
+	azure := testVideo("ccccccccccc", "Azure landing zones")
+	sql := testVideo("ddddddddddd", "SQL joins")
+
+	rolled := rollUp(rules.parents(), []CategorizedVideos{
+		{Category: "Cloud"},
+		{Category: "AWS", Videos: []Video{aws}},
+		{Category: "Lambda", Videos: []Video{lambda}},
+		{Category: "Azure", Videos: []Video{azure}},
+		{Category: "Databases", Videos: []Video{sql}},
+	})
+
+	want := map[string]int{"Cloud": 3, "AWS": 2, "Lambda": 1, "Azure": 1, "Databases": 1}
+	for category, count := range want {
+		if len(rolled[category]) != count {
+			t.Errorf("%s has %d videos, want %d", category, len(rolled[category]), count)
+		}
+	}
+}
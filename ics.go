@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsTimeLayout is the UTC date-time format used in iCalendar files
+const icsTimeLayout = "20060102T150405Z"
+
+// icsEvent is one block of viewing time in the calendar
+type icsEvent struct {
+	UID         string
+	Start       time.Time
+	Seconds     int
+	Summary     string
+	Description string
+	URL         string
+}
+
+// icsEscape escapes a TEXT value as described in RFC 5545 section 3.3.11
+func icsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// icsFold writes a content line, folding it so no line is longer than 75
+// octets without splitting a UTF-8 character
+func icsFold(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// watchPlanEvents turns each day of the plan into calendar events starting at
+// the plan's DayStart, one per video or, when group is "category", one per
+// category per day listing its videos
+func watchPlanEvents(plan watchPlan, group string) ([]icsEvent, error) {
+	dayStart, err := time.Parse("15:04", plan.DayStart)
+	if err != nil {
+		return nil, fmt.Errorf("bad day start %q: %v", plan.DayStart, err)
+	}
+
+	var events []icsEvent
+	for _, day := range plan.Days {
+		date, err := time.ParseInLocation(planDateLayout, day.Date, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		// Built from the wall clock so the start stays put on days the clocks change
+		start := time.Date(date.Year(), date.Month(), date.Day(), dayStart.Hour(), dayStart.Minute(), 0, 0, time.Local)
+
+		if group != "category" {
+			for _, video := range day.Videos {
+				events = append(events, icsEvent{
+					UID:     video.ID + "-" + day.Date,
+					Start:   start,
+					Seconds: video.DurationSeconds,
+					Summary: video.Title,
+					Description: fmt.Sprintf("%s\nLength: %s\nCategory: %s\nChannel: %s",
+						video.Link, formatDuration(video.DurationSeconds), video.Category, orDefault(video.Channel, "?")),
+					URL: video.Link,
+				})
+				start = start.Add(time.Duration(video.DurationSeconds) * time.Second)
+			}
+			continue
+		}
+
+		// One batch per category, in the order the plan first reaches it
+		var order []string
+		batches := make(map[string]*icsEvent)
+		counts := make(map[string]int)
+		for _, video := range day.Videos {
+			batch, ok := batches[video.Category]
+			if !ok {
+				order = append(order, video.Category)
+				batch = &icsEvent{UID: strings.ReplaceAll(strings.ToLower(video.Category), " ", "-") + "-" + day.Date}
+				batches[video.Category] = batch
+			}
+			batch.Seconds += video.DurationSeconds
+			counts[video.Category]++
+			batch.Description += fmt.Sprintf("%s (%s)\n%s\n\n", video.Title, formatDuration(video.DurationSeconds), video.Link)
+		}
+		for _, category := range order {
+			batch := batches[category]
+			batch.Start = start
+			batch.Summary = fmt.Sprintf("%s (%d videos)", category, counts[category])
+			batch.Description = "Category: " + category + "\n\n" + strings.TrimSpace(batch.Description)
+			events = append(events, *batch)
+			start = start.Add(time.Duration(batch.Seconds) * time.Second)
+		}
+	}
+	return events, nil
+}
+
+// icsWatchPlan renders the plan as an iCalendar file that can be imported
+// into any calendar client
+func icsWatchPlan(plan watchPlan, group string) (string, error) {
+	events, err := watchPlanEvents(plan, group)
+	if err != nil {
+		return "", err
+	}
+
+	stamp := time.Now().UTC().Format(icsTimeLayout)
+	var b strings.Builder
+	icsFold(&b, "BEGIN:VCALENDAR")
+	icsFold(&b, "VERSION:2.0")
+	icsFold(&b, "PRODID:-//youtube-watch-later-mess//watch plan//EN")
+	icsFold(&b, "CALSCALE:GREGORIAN")
+	icsFold(&b, "X-WR-CALNAME:Watch plan")
+	for _, event := range events {
+		icsFold(&b, "BEGIN:VEVENT")
+		icsFold(&b, "UID:"+event.UID+"@youtube-watch-later-mess")
+		icsFold(&b, "DTSTAMP:"+stamp)
+		icsFold(&b, "DTSTART:"+event.Start.UTC().Format(icsTimeLayout))
+		icsFold(&b, "DTEND:"+event.Start.Add(time.Duration(event.Seconds)*time.Second).UTC().Format(icsTimeLayout))
+		icsFold(&b, "SUMMARY:"+icsEscape(event.Summary))
+		icsFold(&b, "DESCRIPTION:"+icsEscape(event.Description))
+		if event.URL != "" {
+			icsFold(&b, "URL:"+event.URL)
+		}
+		icsFold(&b, "TRANSP:OPAQUE")
+		icsFold(&b, "END:VEVENT")
+	}
+	icsFold(&b, "END:VCALENDAR")
+	return b.String(), nil
+}
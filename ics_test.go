@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchPlanEventsStartAtDayStartAcrossClockChanges(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	local := time.Local
+	time.Local = berlin
+	t.Cleanup(func() { time.Local = local })
+
+	// The clocks go forward on 31 March 2024 and back on 27 October 2024
+	plan := watchPlan{DayStart: "19:30"}
+	for _, date := range []string{"2024-03-30", "2024-03-31", "2024-10-27"} {
+		plan.Days = append(plan.Days, watchPlanDay{Date: date, Videos: []plannedVideo{
+			{ID: "aaaaaaaaaaa", Title: "Helm charts", Category: "Kubernetes", DurationSeconds: 600},
+		}})
+	}
+
+	for _, group := range []string{"video", "category"} {
+		events, err := watchPlanEvents(plan, group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("%s: got %d events, want 3", group, len(events))
+		}
+		for i, event := range events {
+			start := event.Start.In(berlin)
+			if start.Format("2006-01-02 15:04") != plan.Days[i].Date+" 19:30" {
+				t.Errorf("%s: event %d starts %s, want %s 19:30", group, i, start.Format("2006-01-02 15:04"), plan.Days[i].Date)
+			}
+		}
+	}
+}
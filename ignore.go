@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// shortMaxSeconds is the longest a video can be and still count as a short
+// when its link doesn't say
+const shortMaxSeconds = 60
+
+// IgnoreRules leave videos out of every category and playlist before the
+// keywords are matched. An override brings an ignored video back.
+type IgnoreRules struct {
+	// Channels are channel names to ignore, ignoring case
+	Channels []string `json:"channels,omitempty"`
+
+	// Shorts ignores YouTube Shorts, Livestreams ignores live streams and
+	// their replays
+	Shorts      bool `json:"shorts,omitempty"`
+	Livestreams bool `json:"livestreams,omitempty"`
+
+	// TitlePatterns are regular expressions; videos whose title matches one
+	// are ignored
+	TitlePatterns []string `json:"titlePatterns,omitempty"`
+
+	// titlePatterns are the compiled TitlePatterns, set by validate
+	titlePatterns []*regexp.Regexp
+}
+
+// validate compiles the title patterns
+func (i *IgnoreRules) validate() error {
+	i.titlePatterns = nil
+	for _, pattern := range i.TitlePatterns {
+		compiled, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return fmt.Errorf("ignore title pattern %q: %v", pattern, err)
+		}
+		i.titlePatterns = append(i.titlePatterns, compiled)
+	}
+	return nil
+}
+
+// reason returns why the rules ignore a video, or "" if they don't
+func (i *IgnoreRules) reason(video Video) string {
+	if i == nil {
+		return ""
+	}
+	switch {
+	case video.Channel != "" && containsFold(i.Channels, video.Channel):
+		return "channel " + video.Channel
+	case i.Shorts && isShort(video):
+		return "short"
+	case i.Livestreams && isLivestream(video):
+		return "livestream"
+	}
+	for n, pattern := range i.titlePatterns {
+		if pattern.MatchString(video.Title) {
+			return fmt.Sprintf("title matches %q", i.TitlePatterns[n])
+		}
+	}
+	return ""
+}
+
+// isShort reports whether a video is a YouTube Short, by its link or, failing
+// that, its length
+func isShort(video Video) bool {
+	return strings.Contains(video.Link, "/shorts/") || (video.DurationSeconds > 0 && video.DurationSeconds <= shortMaxSeconds)
+}
+
+// isLivestream reports whether a video is live, upcoming or a replay of a
+// stream, which the ariaLabel shows as "Streamed 2 weeks ago"
+func isLivestream(video Video) bool {
+	return strings.HasPrefix(video.Age, "Streamed ") || video.LiveBroadcastContent == "live" || video.LiveBroadcastContent == "upcoming"
+}
+
+// printIgnored prints how many videos the ignore rules left out, by reason
+func printIgnored(videos []Video, rules *Rules, overrides map[string]string) {
+	counts := make(map[string]int)
+	var reasons []string
+	for _, video := range videos {
+		if _, overridden := overrides[videoKey(video)]; overridden {
+			continue
+		}
+		if reason := rules.Ignore.reason(video); reason != "" {
+			if counts[reason] == 0 {
+				reasons = append(reasons, reason)
+			}
+			counts[reason]++
+		}
+	}
+	for _, reason := range reasons {
+		fmt.Printf("  Ignored, %s: %d\n", reason, counts[reason])
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import "testing"
+
+func TestCategoryRuleMask(t *testing.T) {
+	rules := &Rules{Categories: []CategoryRule{{
+		Name:            "Programming",
+		Keywords:        []string{"go", "ide"},
+		Exclude:         []string{"let's go", "Go Karts"},
+		ExcludePatterns: []string{`\Bgo|go\B`, `\Bide|ide\B`},
+	}}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	category := rules.Categories[0]
+
+	// mask is given lowercased text, as score does
+	tests := []struct{ text, want string }{
+		{"learn go", "learn go"},
+		{"let's go!", "\x00!"},
+		{"go karts", "\x00"},
+		{"good habits", "\x00od habits"},
+		{"mango", "man\x00"},
+		{"video ideas", "v\x00o \x00as"},
+		{"ide setup", "ide setup"},
+		{"let's go learn go", "\x00 learn go"},
+	}
+	for _, tt := range tests {
+		if got := category.mask(tt.text); got != tt.want {
+			t.Errorf("mask(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExcludedKeywords(t *testing.T) {
+	rules := &Rules{Categories: []CategoryRule{{
+		Name:            "Programming",
+		Keywords:        []string{"go", "ide"},
+		Exclude:         []string{"let's go"},
+		ExcludePatterns: []string{`\Bgo|go\B`, `\Bide|ide\B`},
+	}}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Learn Go in an hour", true},
+		{"Let's Go!", false},
+		{"Let's go learn Go", true},
+		{"Good habits", false},
+		{"Video ideas", false},
+		{"Setting up an IDE", true},
+	}
+	for _, tt := range tests {
+		score, _ := rules.Categories[0].score(testVideo("aaaaaaaaaaa", tt.title), rules.Fields)
+		if got := score > 0; got != tt.want {
+			t.Errorf("%q matched = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreReason(t *testing.T) {
+	ignore := &IgnoreRules{Channels: []string{"Some Channel"}, Shorts: true, Livestreams: true, TitlePatterns: []string{"^#shorts", "live stream"}}
+	if err := ignore.validate(); err != nil {
+		t.Fatal(err)
+	}
+	ok := testVideo("aaaaaaaaaaa", "Helm charts")
+	with := func(change func(*Video)) Video {
+		video := ok
+		change(&video)
+		return video
+	}
+
+	tests := []struct {
+		name  string
+		video Video
+		want  string
+	}{
+		{"kept", ok, ""},
+		{"channel", with(func(v *Video) { v.Channel = "some channel" }), "channel some channel"},
+		{"other channel", with(func(v *Video) { v.Channel = "Another Channel" }), ""},
+		{"shorts link", with(func(v *Video) { v.Link = "https://www.youtube.com/shorts/aaaaaaaaaaa" }), "short"},
+		{"a minute long", with(func(v *Video) { v.DurationSeconds = 60 }), "short"},
+		{"just over a minute", with(func(v *Video) { v.DurationSeconds = 61 }), ""},
+		{"stream replay", with(func(v *Video) { v.Age = "Streamed 2 weeks ago" }), "livestream"},
+		{"upcoming", with(func(v *Video) { v.LiveBroadcastContent = "upcoming" }), "livestream"},
+		{"title pattern", with(func(v *Video) { v.Title = "#Shorts helm tip" }), `title matches "^#shorts"`},
+		{"second title pattern", with(func(v *Video) { v.Title = "Helm Live Stream" }), `title matches "live stream"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ignore.reason(tt.video); got != tt.want {
+				t.Errorf("reason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Shorts and livestreams are only ignored when asked to
+	short := with(func(v *Video) { v.DurationSeconds = 30; v.Age = "Streamed 2 weeks ago" })
+	if got := (&IgnoreRules{}).reason(short); got != "" {
+		t.Errorf("empty rules: reason = %q, want none", got)
+	}
+	var none *IgnoreRules
+	if got := none.reason(short); got != "" {
+		t.Errorf("nil rules: reason = %q, want none", got)
+	}
+}
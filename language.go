@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// defaultLanguage is the language videos are assumed to be in when rules.json
+// doesn't set languages.primary
+const defaultLanguage = "en"
+
+// languageNames name the per-language categories when languages.names doesn't
+var languageNames = map[string]string{
+	"de": "German", "en": "English", "es": "Spanish", "fr": "French",
+	"it": "Italian", "nl": "Dutch", "pt": "Portuguese",
+}
+
+// languageWords are short, common words that give away the language of a
+// title. Words shared by several languages count for each of them.
+var languageWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "for", "with", "how", "what", "you", "your", "why", "from", "on", "this", "are", "my", "it", "get"},
+	"de": {"der", "die", "das", "und", "ist", "mit", "für", "ein", "eine", "wie", "nicht", "auf", "den", "dem", "zu", "von", "ich", "wir", "mehr", "oder", "auch", "was", "warum", "im", "einfach", "erklärt"},
+	"es": {"el", "la", "los", "las", "de", "del", "y", "en", "con", "para", "por", "que", "una", "un", "es", "cómo", "qué", "como", "tu", "más", "sin", "al", "lo"},
+	"pt": {"o", "a", "os", "as", "de", "do", "da", "dos", "das", "e", "em", "com", "para", "por", "que", "um", "uma", "é", "como", "não", "mais", "você", "seu", "sua", "no", "na", "ao"},
+	"fr": {"le", "la", "les", "des", "du", "de", "et", "en", "un", "une", "est", "pour", "avec", "dans", "sur", "que", "qui", "comment", "pas", "ce", "vous", "au"},
+	"it": {"il", "lo", "la", "gli", "le", "di", "del", "della", "e", "è", "con", "per", "che", "un", "una", "come", "non", "sono", "nel"},
+	"nl": {"de", "het", "een", "en", "van", "met", "voor", "is", "op", "hoe", "wat", "niet", "je", "ook", "waarom"},
+}
+
+// languageLetters are letters only, or mostly, one language uses; each one
+// found counts twice as much as a common word
+var languageLetters = map[string][]string{
+	"de": {"ä", "ö", "ü", "ß"},
+	"es": {"ñ", "¿", "¡"},
+	"pt": {"ã", "õ"},
+	"fr": {"è", "ê", "ë", "ù", "œ"},
+	"it": {"ò", "ì"},
+}
+
+// languageEndings are word endings typical of one language
+var languageEndings = map[string][]string{
+	"de": {"ung", "ungen", "keit", "lich"},
+	"es": {"ción", "ciones", "dad"},
+	"pt": {"ção", "ções"},
+	"it": {"zione", "zioni"},
+}
+
+// LanguageRules route videos that aren't in the primary language, see language.go
+type LanguageRules struct {
+	// Primary is the language most videos are in, "en" by default
+	Primary string `json:"primary,omitempty"`
+
+	// Playlists puts videos in any other language into one category (and so
+	// one playlist) per language instead of sorting them by topic
+	Playlists bool `json:"playlists,omitempty"`
+
+	// Names names the per-language categories, e.g. {"de": "German talks"};
+	// the English name of the language is used otherwise
+	Names map[string]string `json:"names,omitempty"`
+}
+
+// primary returns the primary language
+func (l *LanguageRules) primary() string {
+	if l == nil || l.Primary == "" {
+		return defaultLanguage
+	}
+	return l.Primary
+}
+
+// category returns the per-language category a video in language belongs
+// in, or "" if videos in that language are sorted by topic
+func (l *LanguageRules) category(language string) string {
+	if l == nil || !l.Playlists || language == "" || language == l.primary() {
+		return ""
+	}
+	if name := l.Names[language]; name != "" {
+		return name
+	}
+	if name := languageNames[language]; name != "" {
+		return name
+	}
+	return strings.ToUpper(language)
+}
+
+// categories returns the per-language categories videos can end up in, by
+// language code: every language named in Names or languageNames except the
+// primary one
+func (l *LanguageRules) categories() map[string]string {
+	names := make(map[string]string)
+	if l == nil || !l.Playlists {
+		return names
+	}
+	for language := range languageNames {
+		names[language] = l.category(language)
+	}
+	for language := range l.Names {
+		names[language] = l.category(language)
+	}
+	delete(names, l.primary())
+	return names
+}
+
+// isCategory reports whether name is one of the per-language categories
+func (l *LanguageRules) isCategory(name string) bool {
+	for _, category := range l.categories() {
+		if category == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the per-language category names, including the default
+// ones, don't clash with categories
+func (l *LanguageRules) validate(categories []string) error {
+	names := l.categories()
+	languages := make([]string, 0, len(names))
+	for language := range names {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		name := names[language]
+		if containsString(categories, name) || name == otherCategory || name == excludeCategory {
+			return fmt.Errorf("languages: name %q for %q is already a category", name, language)
+		}
+	}
+	return nil
+}
+
+// normalizeLanguage turns a language tag like "de-DE" into "de"
+func normalizeLanguage(tag string) string {
+	language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	return language
+}
+
+// detectLanguage returns a video's language: the audio or metadata language
+// from the API when enrich has filled it in, otherwise a guess from the
+// title, or "" if the title gives nothing away
+func detectLanguage(video Video) string {
+	for _, tag := range []string{video.DefaultAudioLanguage, video.DefaultLanguage} {
+		if language := normalizeLanguage(tag); language != "" && language != "zxx" {
+			return language
+		}
+	}
+	return guessLanguage(video.Title)
+}
+
+// guessLanguage scores text against each language's common words, letters
+// and endings. The best language must beat the runner up, so "de la" guesses
+// nothing.
+func guessLanguage(text string) string {
+	text = strings.ToLower(text)
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+
+	best, bestScore, runnerUp := "", 0, 0
+	for _, language := range []string{"en", "de", "es", "pt", "fr", "it", "nl"} {
+		score := 0
+		for _, word := range words {
+			if containsString(languageWords[language], word) {
+				score++
+			}
+			for _, ending := range languageEndings[language] {
+				if strings.HasSuffix(word, ending) && len(word) > len(ending)+2 {
+					score++
+				}
+			}
+		}
+		for _, letter := range languageLetters[language] {
+			if strings.Contains(text, letter) {
+				score += 2
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUp = language, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if bestScore == runnerUp {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestLanguageRulesValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		languages  *LanguageRules
+		categories []string
+		wantErr    bool
+	}{
+		{"no clash", &LanguageRules{Playlists: true}, []string{"Programming"}, false},
+		{"default name clashes", &LanguageRules{Playlists: true}, []string{"German"}, true},
+		{"own name clashes", &LanguageRules{Playlists: true, Names: map[string]string{"ja": "Programming"}}, []string{"Programming"}, true},
+		{"renamed default", &LanguageRules{Playlists: true, Names: map[string]string{"de": "German talks"}}, []string{"German"}, false},
+		{"primary language is sorted by topic", &LanguageRules{Playlists: true, Primary: "de"}, []string{"German"}, false},
+		{"names clash with Other", &LanguageRules{Playlists: true, Names: map[string]string{"fr": otherCategory}}, nil, true},
+		{"playlists off", &LanguageRules{}, []string{"German"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.languages.validate(tt.categories)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidOverrideAcceptsLanguageCategories(t *testing.T) {
+	rules := &Rules{
+		Categories: []CategoryRule{{Name: "Programming"}},
+		Languages:  &LanguageRules{Playlists: true, Names: map[string]string{"es": "Charlas en español", "ja": "Japanese"}},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, category := range []string{"Programming", otherCategory, excludeCategory, "German", "Charlas en español", "Japanese"} {
+		if !validOverride(rules, category) {
+			t.Errorf("validOverride(%q) = false", category)
+		}
+	}
+	for _, category := range []string{"English", "Spanish", "Cooking"} {
+		if validOverride(rules, category) {
+			t.Errorf("validOverride(%q) = true", category)
+		}
+	}
+}
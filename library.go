@@ -0,0 +1,291 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// libraryMigrations are applied in order and tracked with PRAGMA user_version,
+// so new tables or columns are added by appending to this list
+var libraryMigrations = []string{
+	`CREATE TABLE videos (
+		id             TEXT PRIMARY KEY,
+		title          TEXT NOT NULL,
+		link           TEXT NOT NULL,
+		aria_label     TEXT NOT NULL DEFAULT '',
+		first_seen     TEXT NOT NULL DEFAULT '',
+		last_seen      TEXT NOT NULL DEFAULT '',
+		position       INTEGER NOT NULL DEFAULT 0,
+		in_watch_later INTEGER NOT NULL DEFAULT 1
+	);
+	CREATE TABLE metadata (
+		video_id   TEXT PRIMARY KEY REFERENCES videos(id),
+		data       TEXT NOT NULL,
+		fetched_at TEXT NOT NULL
+	);
+	CREATE TABLE categories (
+		video_id    TEXT PRIMARY KEY REFERENCES videos(id),
+		category    TEXT NOT NULL,
+		assigned_at TEXT NOT NULL
+	);
+	CREATE TABLE overrides (
+		video_id   TEXT PRIMARY KEY,
+		category   TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+	CREATE TABLE playlists (
+		category    TEXT PRIMARY KEY,
+		playlist_id TEXT NOT NULL,
+		title       TEXT NOT NULL,
+		created_at  TEXT NOT NULL
+	);
+	CREATE TABLE playlist_items (
+		playlist_id TEXT NOT NULL,
+		video_id    TEXT NOT NULL,
+		added_at    TEXT NOT NULL,
+		PRIMARY KEY (playlist_id, video_id)
+	);
+	CREATE TABLE sync_runs (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at  TEXT NOT NULL,
+		finished_at TEXT NOT NULL DEFAULT '',
+		added       INTEGER NOT NULL DEFAULT 0,
+		error       TEXT NOT NULL DEFAULT ''
+	);`,
+}
+
+// Library is the local SQLite store of videos, categories, overrides,
+// playlists and sync history shared by every command
+type Library struct {
+	db *sql.DB
+}
+
+// openLibrary opens (creating if needed) the library database and brings its schema up to date
+func openLibrary(filename string) (*Library, error) {
+	db, err := sql.Open("sqlite", filename+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	lib := &Library{db: db}
+	if err := lib.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating library %s: %v", filename, err)
+	}
+	return lib, nil
+}
+
+// Close closes the underlying database
+func (l *Library) Close() error {
+	return l.db.Close()
+}
+
+// migrate applies any migrations newer than the database's user_version
+func (l *Library) migrate() error {
+	var version int
+	if err := l.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(libraryMigrations); i++ {
+		tx, err := l.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(libraryMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// now returns the current time in the format stored in the library
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// importScrape records the videos from the latest scrape as being in Watch
+// Later (in scrape order) and marks removed videos as gone. First seen dates
+// only ever move earlier so history from previous runs is kept.
+func (l *Library) importScrape(current, removed []Video) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE videos SET in_watch_later = 0"); err != nil {
+		return err
+	}
+
+	upsert := `INSERT INTO videos (id, title, link, aria_label, first_seen, last_seen, position, in_watch_later)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			title = excluded.title,
+			link = excluded.link,
+			aria_label = excluded.aria_label,
+			first_seen = MIN(videos.first_seen, excluded.first_seen),
+			last_seen = MAX(videos.last_seen, excluded.last_seen),
+			position = excluded.position,
+			in_watch_later = excluded.in_watch_later`
+
+	for i, video := range current {
+		if _, err := tx.Exec(upsert, videoKey(video), video.Title, video.Link, video.AriaLabel, video.FirstSeen, video.LastSeen, i+1, 1); err != nil {
+			return err
+		}
+	}
+	for _, video := range removed {
+		if _, err := tx.Exec(upsert, videoKey(video), video.Title, video.Link, video.AriaLabel, video.FirstSeen, video.LastSeen, 0, 0); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// watchLaterVideos returns the videos currently in Watch Later in scrape order
+func (l *Library) watchLaterVideos() ([]Video, error) {
+	return l.queryVideos("WHERE in_watch_later = 1 ORDER BY position")
+}
+
+// overriddenVideos returns every video with a manual override, including
+// ones that have since left Watch Later
+func (l *Library) overriddenVideos() ([]Video, error) {
+	return l.queryVideos("WHERE videos.id IN (SELECT video_id FROM overrides) ORDER BY position")
+}
+
+// sortedVideos returns the videos in Watch Later plus any others with an
+// override, which together show what belongs in each category
+func (l *Library) sortedVideos() ([]Video, error) {
+	return l.queryVideos("WHERE in_watch_later = 1 OR videos.id IN (SELECT video_id FROM overrides) ORDER BY position")
+}
+
+// queryVideos loads videos using the given WHERE/ORDER BY clause
+func (l *Library) queryVideos(clause string) ([]Video, error) {
+	rows, err := l.db.Query(`SELECT id, title, link, aria_label, first_seen, last_seen, m.data
+		FROM videos LEFT JOIN metadata m ON m.video_id = videos.id ` + clause)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []Video
+	for rows.Next() {
+		var video Video
+		var metadata sql.NullString
+		if err := rows.Scan(&video.ID, &video.Title, &video.Link, &video.AriaLabel, &video.FirstSeen, &video.LastSeen, &metadata); err != nil {
+			return nil, err
+		}
+		parseAriaLabel(&video)
+		if err := decodeMetadata(&video, metadata); err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+// saveCategories replaces the category assignments with the result of the latest categorization
+func (l *Library) saveCategories(categorizedVideos []CategorizedVideos) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM categories"); err != nil {
+		return err
+	}
+
+	assignedAt := now()
+	for _, catVideos := range categorizedVideos {
+		for _, video := range catVideos.Videos {
+			if _, err := tx.Exec("INSERT OR REPLACE INTO categories (video_id, category, assigned_at) VALUES (?, ?, ?)",
+				videoKey(video), catVideos.Category, assignedAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// playlistID returns the YouTube playlist ID previously created for a category, if any
+func (l *Library) playlistID(category string) (string, error) {
+	var id string
+	err := l.db.QueryRow("SELECT playlist_id FROM playlists WHERE category = ?", category).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// savePlaylist records the YouTube playlist created for a category
+func (l *Library) savePlaylist(category, playlistID, title string) error {
+	_, err := l.db.Exec("INSERT OR REPLACE INTO playlists (category, playlist_id, title, created_at) VALUES (?, ?, ?, ?)",
+		category, playlistID, title, now())
+	return err
+}
+
+// playlistVideos returns the set of video IDs already added to a playlist
+func (l *Library) playlistVideos(playlistID string) (map[string]bool, error) {
+	rows, err := l.db.Query("SELECT video_id FROM playlist_items WHERE playlist_id = ?", playlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	videos := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		videos[id] = true
+	}
+	return videos, rows.Err()
+}
+
+// savePlaylistItem records a video added to a playlist so later syncs skip it
+func (l *Library) savePlaylistItem(playlistID, videoID string) error {
+	_, err := l.db.Exec("INSERT OR IGNORE INTO playlist_items (playlist_id, video_id, added_at) VALUES (?, ?, ?)",
+		playlistID, videoID, now())
+	return err
+}
+
+// deletePlaylistItem forgets a video removed from a playlist
+func (l *Library) deletePlaylistItem(playlistID, videoID string) error {
+	_, err := l.db.Exec("DELETE FROM playlist_items WHERE playlist_id = ? AND video_id = ?", playlistID, videoID)
+	return err
+}
+
+// startSync records the start of a playlist sync and returns its ID
+func (l *Library) startSync() (int64, error) {
+	result, err := l.db.Exec("INSERT INTO sync_runs (started_at) VALUES (?)", now())
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// finishSync records how a playlist sync ended
+func (l *Library) finishSync(id int64, added int, syncErr error) error {
+	message := ""
+	if syncErr != nil {
+		message = syncErr.Error()
+	}
+	_, err := l.db.Exec("UPDATE sync_runs SET finished_at = ?, added = ?, error = ? WHERE id = ?",
+		now(), added, message, id)
+	return err
+}
@@ -3,23 +3,69 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
 type Video struct {
+	ID        string `json:"id,omitempty"`
 	Title     string `json:"title"`
 	Link      string `json:"link"`
 	AriaLabel string `json:"ariaLabel"`
+	FirstSeen string `json:"firstSeen,omitempty"`
+	LastSeen  string `json:"lastSeen,omitempty"`
+
+	// Parsed from the ariaLabel
+	Channel         string `json:"channel,omitempty"`
+	Views           int64  `json:"views,omitempty"`
+	Age             string `json:"age,omitempty"`
+	DurationSeconds int    `json:"durationSeconds,omitempty"`
+
+	// From the YouTube Data API, see enrich.go
+	Description          string   `json:"description,omitempty"`
+	Tags                 []string `json:"tags,omitempty"`
+	ChannelID            string   `json:"channelId,omitempty"`
+	CategoryID           string   `json:"categoryId,omitempty"`
+	Duration             string   `json:"duration,omitempty"`
+	PublishedAt          string   `json:"publishedAt,omitempty"`
+	LiveBroadcastContent string   `json:"liveBroadcastContent,omitempty"`
+	DefaultLanguage      string   `json:"defaultLanguage,omitempty"`
+	DefaultAudioLanguage string   `json:"defaultAudioLanguage,omitempty"`
+	PrivacyStatus        string   `json:"privacyStatus,omitempty"`
+	UploadStatus         string   `json:"uploadStatus,omitempty"`
+	RegionAllowed        []string `json:"regionAllowed,omitempty"`
+	RegionBlocked        []string `json:"regionBlocked,omitempty"`
+	NotFound             bool     `json:"notFound,omitempty"`
+
+	// Why the video can't be synced, see unavailable.go
+	Unavailable string `json:"unavailable,omitempty"`
+
+	// Set by categorizeVideos
+	Language        string             `json:"language,omitempty"`
+	Score           float64            `json:"score,omitempty"`
+	MatchedKeywords []string           `json:"matchedKeywords,omitempty"`
+	Scores          map[string]float64 `json:"scores,omitempty"`
+	Override        string             `json:"override,omitempty"`
+
+	// Set by categorizeVideos when the classifier is on, see classifier.go
+	Prediction           string  `json:"prediction,omitempty"`
+	PredictionConfidence float64 `json:"predictionConfidence,omitempty"`
+
+	// Set by categorizeVideos when embeddings are on, see embeddings.go
+	SemanticCategory string  `json:"semanticCategory,omitempty"`
+	Similarity       float64 `json:"similarity,omitempty"`
 }
 
 type CategorizedVideos struct {
@@ -27,64 +73,200 @@ type CategorizedVideos struct {
 	Videos   []Video `json:"videos"`
 }
 
+// otherCategory collects the videos no rule matched
+const otherCategory = "Other"
+
+// commands maps subcommand names to their entry points. Running without a
+// subcommand categorizes the Watch Later videos and syncs the playlists.
+var commands = map[string]func(args []string){
+	"override":    runOverride,
+	"review":      runReview,
+	"serve":       runServe,
+	"enrich":      runEnrich,
+	"unavailable": runUnavailable,
+	"stats":       runStats,
+	"plan":        runPlan,
+	"train":       runTrain,
+	"eval":        runEval,
+	"discover":    runDiscover,
+	"patch":       runPatch,
+	"suggest":     runSuggest,
+}
+
 func main() {
-	// Read and parse scrape.json
-	videos, err := readScrapeJSON("scrape.json")
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+	runSort(os.Args[1:])
+}
+
+// runSort imports the scrapes, categorizes the Watch Later videos and syncs a playlist per category
+func runSort(args []string) {
+	flags := flag.NewFlagSet("sort", flag.ExitOnError)
+	scrapePaths := flags.String("scrape", "scrape.json", "comma-separated list of scrape files or directories of scrapes to merge (empty to use the library as is)")
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	dryRun := flags.Bool("dry-run", false, "show which playlists would be created and which videos added without calling YouTube")
+	enrich := flags.Bool("enrich", false, "fetch descriptions, tags, durations and statistics from the YouTube Data API before categorizing")
+	region := flags.String("region", "", "ISO 3166-1 country code to check region restrictions against, e.g. GB")
+	maxAge := flags.Duration("max-age", 30*24*time.Hour, "with -enrich, re-fetch metadata cached longer ago than this")
+	formatFlag := flags.String("format", "json", "comma-separated output formats for categorized_videos: "+strings.Join(reportFormatNames(), ", "))
+	flags.Parse(args)
+
+	formats, err := parseReportFormats(*formatFlag)
+	if err != nil {
+		log.Fatalf("Error parsing -format: %v", err)
+	}
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	// Open the local library
+	lib, err := openLibrary(*libraryPath)
 	if err != nil {
-		log.Fatalf("Error reading scrape.json: %v", err)
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	var scrapeFiles []string
+	if *scrapePaths != "" {
+		// Read and merge every scrape, oldest first
+		scrapeFiles, err = collectScrapeFiles(strings.Split(*scrapePaths, ","))
+		if err != nil {
+			log.Fatalf("Error finding scrape files: %v", err)
+		}
+
+		current, removed, err := mergeScrapes(scrapeFiles)
+		if err != nil {
+			log.Fatalf("Error reading scrapes: %v", err)
+		}
+
+		// Report videos that have gone from Watch Later since an earlier scrape
+		if len(removed) > 0 {
+			fmt.Printf("Videos no longer in Watch Later: %d\n", len(removed))
+			for _, video := range removed {
+				fmt.Printf("  %s (last seen %s)\n", video.Title, video.LastSeen)
+			}
+			if err := saveVideos("removed_videos.json", removed); err != nil {
+				log.Fatalf("Error saving removed videos: %v", err)
+			}
+		}
+
+		if err := lib.importScrape(current, removed); err != nil {
+			log.Fatalf("Error saving scrape to library: %v", err)
+		}
+	}
+
+	// Fill in metadata from the YouTube Data API, reusing anything cached
+	var service *youtube.Service
+	if *enrich {
+		service = newYouTubeService()
+		if _, err := enrichVideos(service, lib, *maxAge); err != nil {
+			log.Fatalf("Error enriching videos: %v", err)
+		}
+	}
+
+	// Load the current Watch Later videos from the library
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
 	}
 
 	// Print the number of videos
 	fmt.Printf("Number of videos: %d\n", len(videos))
 
-	// Define category keywords
-	categoryKeywords := map[string][]string{
-		"Programming & Development":           {"Coded", "VS Code", "YAML", "programming", "development", "coding", "go", "python", "java", "javascript", "Devcontainers", "vscode", "visual studio code", "intellij", "eclipse", "netbeans", "atom", "sublime text", "vim", "emacs", "code editor", "ide", "integrated development environment", "developer", "Angular", "Node.js", "TypeScript", "Stripe"},
-		"Cloud & Infrastructure":              {"cloud", "infrastructure", "aws", "azure", "gcp", "google", "cloud platform", "cloud services", "cloud computing", "cloud storage", "cloud networking", "cloud security", "cloud databases", "cloud migration", "cloud architecture", "cloud design", "cloud deployment", "cloud management", "cloud monitoring", "cloud scaling", "cloud optimization", "cloud performance", "cloud reliability", "cloud availability", "cloud fault tolerance", "cloud disaster recovery", "cloud backup", "cloud restore", "cloud pricing", "cloud billing", "cloud cost management", "cloud governance", "cloud compliance", "cloud audits", "cloud reviews", "cloud ratings", "cloud rankings", "cloud awards", "cloud recognition", "cloud certifications", "cloud badges", "cloud labels", "cloud tags", "cloud categories", "cloud topics", "cloud subjects", "cloud areas", "cloud domains", "cloud fields", "cloud industries", "cloud sectors", "cloud verticals", "cloud markets", "cloud audiences", "cloud users", "cloud developers", "cloud architects", "cloud engineers", "cloud administrators", "cloud operators", "cloud managers", "cloud directors", "cloud leads", "cloud officers", "cloud coordinators", "cloud specialists", "cloud consultants", "cloud advisors", "cloud partners", "cloud vendors", "cloud customers", "cloud clients", "cloud consumers", "cloud producers", "cloud providers", "cloud services", "cloud solutions", "cloud products", "cloud offerings", "cloud features", "cloud capabilities", "cloud integrations", "cloud extensions", "cloud plugins", "cloud modules", "cloud packages", "cloud dependencies", "cloud security", "cloud compliance", "cloud audits", "cloud reviews", "cloud ratings", "cloud rankings", "cloud awards", "cloud recognition", "cloud certifications", "cloud badges", "cloud labels", "cloud tags", "cloud categories", "cloud topics", "cloud subjects", "cloud areas", "cloud domains", "cloud fields", "cloud industries", "cloud sectors", "cloud verticals", "cloud markets", "cloud audiences", "cloud users", "cloud developers", "cloud architects", "cloud engineers", "cloud administrators", "cloud operators", "cloud managers", "cloud directors", "cloud leads", "cloud officers", "cloud coordinators", "cloud specialists", "cloud consultants", "cloud advisors", "cloud partners", "cloud vendors", "cloud customers", "cloud clients", "cloud consumers"},
-		"DevOps and CI/CD":                    {"Vault", "Ansible", "secrets management", "HashiCorp", "Secrets Management", "devops", "ci/cd", "continuous integration", "continuous delivery", "terraform", "platform engineering", "site reliability engineering", "sre", "devops engineer", "devops architect", "devops consultant", "devops specialist", "devops manager", "devops director", "devops lead", "devops officer", "devops coordinator", "devops tools", "devops practices", "devops principles", "devops culture", "devops automation", "devops monitoring", "devops scaling", "devops optimization", "devops performance", "devops reliability", "devops availability", "devops fault tolerance", "devops disaster recovery", "devops backup", "devops restore", "devops security", "devops compliance", "devops audits", "devops reviews", "devops ratings", "devops rankings", "devops awards", "devops recognition", "devops certifications", "devops badges", "devops labels", "devops tags", "devops categories", "devops topics", "devops subjects", "devops areas", "devops domains", "devops fields", "devops industries", "devops sectors", "devops verticals", "devops markets", "devops audiences", "devops users", "devops developers", "devops architects", "devops engineers", "devops administrators", "devops operators", "devops managers", "devops directors", "devops leads", "devops officers", "devops coordinators", "devops specialists", "devops consultants", "devops advisors", "devops partners", "devops vendors", "devops customers", "devops clients", "devops consumers", "devops producers", "devops providers", "devops services", "devops solutions", "devops products", "devops offerings", "devops features", "devops capabilities", "devops integrations", "devops extensions", "devops plugins", "devops modules", "devops packages", "devops dependencies", "devops security", "devops compliance", "devops audits", "devops reviews", "devops ratings", "devops rankings", "devops awards", "devops recognition", "devops certifications", "devops badges", "devops labels", "devops tags", "devops categories", "devops topics", "devops subjects", "devops areas", "devops domains"},
-		"Containers and Kubernetes":           {"Operators", "Talos", "talos", "KubeCon", "Stateful", "microservices", "Helm", "Knative", "OpenShift", "Open Policy Agent", "K8s", "containers", "kubernetes", "docker", "Kubernetes", "Docker", "containerization", "container orchestration", "container management", "container deployment", "container scaling", "container optimization", "container performance", "container reliability", "container availability", "container fault tolerance", "container disaster recovery", "container backup", "container restore", "container security", "container compliance", "container audits", "container reviews", "container ratings", "container rankings", "container awards", "container recognition", "container certifications", "container badges", "container labels", "container tags", "container categories", "container topics", "container subjects", "container areas", "container domains", "container fields", "container industries", "container sectors", "container verticals", "container markets", "container audiences", "container users", "container developers", "container architects", "container engineers", "container administrators", "container operators", "container managers", "container directors", "container leads", "container officers", "container coordinators", "container specialists", "container consultants", "container advisors", "container partners", "container vendors", "container customers", "container clients", "container consumers", "container producers", "container providers", "container services", "container solutions", "container products", "container offerings", "container features", "container capabilities", "container integrations", "container extensions", "container plugins", "container modules", "container packages", "container dependencies", "container security", "container compliance", "container audits", "container reviews", "container ratings", "container rankings", "container awards", "container recognition", "container certifications", "container badges", "container labels", "container tags", "container categories", "container topics", "container subjects", "container areas", "container domains", "container fields", "container industries", "container sectors", "container verticals", "container markets", "container audiences", "container users", "container developers", "container architects", "container engineers", "container administrators", "container operators", "container managers", "container directors", "container leads", "container officers", "container coordinators", "container specialists", "container consultants", "container advisors", "container partners", "container vendors", "container customers", "container clients", "container consumers", "container producers", "container providers", "container services", "container solutions", "container products", "container offerings", "container features", "container capabilities", "container integrations", "container extensions", "container plugins", "container modules", "container packages"},
-		"Data Management and Databases":       {"schema", "Schemas", "DB", "Data Protection", "SurrealDB", "Disaster Recovery", "Storage", "data management", "databases", "sql", "nosql", "mongodb", "postgresql", "MongoDB", "PostgreSQL", "MySQL", "MariaDB", "Cassandra", "Couchbase", "CouchDB", "DynamoDB", "Aurora", "RDS", "Redshift", "BigQuery", "Snowflake", "database"},
-		"Cloud-Native and Serverless":         {"Fermyon", "Service Mesh", "cloud-native", "serverless", "lambda", "functions", "cloud functions", "serverless framework", "cloud run", "cloudflare", "faas", "paas", "saas", "iaas", "cloud computing"},
-		"Security and DevSecOps":              {"Hack", "NIS2", "security", "devsecops", "cybersecurity", "infosec", "information security", "security engineering", "security operations", "security architecture", "security analyst", "security consultant", "security specialist", "security engineer", "security architect", "security operations center", "security operations centre", "security operations analyst", "security operations engineer", "security operations architect", "security operations specialist", "security operations consultant", "security operations manager", "security operations director", "security operations lead", "security operations officer", "security operations coordinator"},
-		"Open Source and Community":           {"Open-Source", "open source", "community", "opensource", "github", "gitlab", "bitbucket", "source control", "version control", "git", "versioning", "gitops", "gitflow", "github actions", "gitlab ci", "bitbucket pipelines", "open source software", "open source projects", "open source contributions", "open source development", "open source licensing", "open source governance", "open source community", "open source ecosystem", "open source tools", "open source technologies", "open source frameworks", "open source libraries", "open source modules", "open source packages", "open source dependencies", "open source security", "open source compliance", "open source audits", "open source reviews", "open source releases", "open source updates", "open source patches", "open source bug fixes", "open source enhancements", "open source features", "open source requests", "open source contributions", "open source pull requests", "open source issues", "open source discussions", "open source forums", "open source chats", "open source meetups", "open source events", "open source conferences", "open source summits", "open source workshops", "open source tutorials", "open source webinars", "open source videos", "open source podcasts", "open source blogs", "open source articles", "open source books", "open source papers", "open source research", "open source studies", "open source surveys", "open source polls", "open source feedback", "open source reviews", "open source ratings", "open source rankings", "open source awards", "open source recognition", "open source certifications", "open source badges", "open source labels", "open source tags", "open source categories", "open source topics", "open source subjects", "open source areas", "open source domains", "open source fields", "open source industries", "open source sectors", "open source verticals", "open source markets", "open source audiences", "open source users", "open source developers", "open source contributors", "open source maintainers", "open source reviewers", "open source approvers", "open source committers", "open source authors", "open source editors", "open source publishers", "open source consumers", "open source producers", "open source providers", "open source consumers", "open source customers", "open source clients", "open source partners", "open source vendors"},
-		"Storytelling and Career Development": {"CTO", "CEO", "Story", "story", "Job", "storytelling", "story telling", "career development", "career", "development", "career growth", "career advancement", "career progression", "career success", "career satisfaction", "career fulfillment", "career happiness", "career wellbeing", "career balance", "career stability", "career security", "career safety", "career health", "career wealth", "career prosperity", "career abundance", "career opportunities", "career options", "career choices", "career decisions", "career planning", "career strategy", "career management", "career leadership", "career mentorship", "career coaching", "career training", "career education", "career learning", "career development", "career growth", "career advancement", "career progression", "career success", "career satisfaction", "career fulfillment", "career happiness", "career wellbeing", "career balance", "career stability", "career security", "career safety", "career health", "career wealth", "career prosperity", "career abundance", "career opportunities", "career options", "career choices", "career decisions", "career planning", "career strategy", "career management", "career leadership", "career mentorship", "career coaching", "career training", "career education", "career learning", "career development", "career growth", "career advancement", "career progression", "career success", "career satisfaction", "career fulfillment", "career happiness", "career wellbeing", "career balance", "career stability", "career security", "career safety", "career health", "career wealth", "career prosperity", "career abundance", "career opportunities", "career options", "career choices", "career decisions", "career planning", "career strategy", "career management", "career leadership", "career mentorship", "career coaching", "career training", "career education", "career learning", "career development", "career growth", "career advancement", "career progression", "career success", "career satisfaction", "career fulfillment", "career happiness", "career wellbeing", "career balance", "career stability", "career security", "career safety", "career health", "career wealth", "career prosperity", "career abundance", "career opportunities", "career options", "career choices", "career decisions", "career planning", "career strategy", "career management", "career leadership", "career mentorship", "career coaching", "career training", "career education", "career learning", "career development", "career growth", "career advancement", "career progression"},
-		"AI and Emerging Technologies":        {"Ai", "AI", "GPT", "LLM", "Ollama", "GPT-3", "ai", "artificial intelligence", "machine learning", "emerging technologies", "blockchain", "quantum computing", "iot", "internet of things", "edge computing", "fog computing", "distributed computing", "distributed systems", "distributed systems design", "distributed systems architecture", "distributed systems engineering", "distributed systems development", "distributed systems operations", "distributed systems management", "distributed systems monitoring", "distributed systems testing", "distributed systems deployment", "distributed systems scaling", "distributed systems performance", "distributed systems optimization", "distributed systems security", "distributed systems reliability", "distributed systems availability", "distributed systems fault tolerance", "distributed systems disaster recovery", "distributed systems backup", "distributed systems restore"},
-		"Tools and Productivity":              {"Tmux", "Canva", "tools", "productivity", "efficiency", "automation", "tooling", "toolchain", "toolset", "toolkit", "toolbox", "toolbelt", "toolbox", "toolbelt", "toolkit", "toolchain", "toolset", "tooling", "automation", "efficiency", "productivity", "tools and productivity", "productivity and tools", "tools for productivity", "productivity tools", "tools for efficiency", "efficiency tools", "tools for automation", "automation tools", "tools for tooling", "tooling tools", "tools for toolchain", "toolchain tools", "tools for toolset", "toolset tools", "tools for toolkit", "toolkit tools", "tools for toolbox", "toolbox tools", "tools for toolbelt", "toolbelt tools", "tools for toolbox", "toolbox tools", "tools for toolbelt", "toolbelt tools", "tools for productivity and efficiency", "productivity and efficiency tools", "tools for productivity and automation", "productivity and automation tools", "tools for productivity and tooling", "productivity and tooling tools", "tools for productivity and toolchain", "productivity and toolchain tools", "tools for productivity and toolset", "productivity and toolset tools", "tools for productivity and toolkit", "productivity and toolkit tools", "tools for productivity and toolbox", "productivity and toolbox tools", "tools for productivity and toolbelt", "productivity and toolbelt tools", "tools for efficiency and automation", "efficiency and automation tools", "tools for efficiency and tooling", "efficiency and tooling tools", "tools for efficiency and toolchain", "efficiency and toolchain tools", "tools for efficiency and toolset", "efficiency and toolset tools", "tools for efficiency and toolkit", "efficiency and toolkit tools", "tools for efficiency and toolbox", "efficiency and toolbox tools", "tools for efficiency and toolbelt", "efficiency and toolbelt tools", "tools for automation and tooling", "automation and tooling tools", "tools for automation and toolchain", "automation and toolchain tools", "tools for automation and toolset", "automation and toolset tools", "tools for automation and toolkit", "automation and toolkit tools", "tools for automation and toolbox", "automation and toolbox tools", "tools for automation and toolbelt", "automation and toolbelt tools", "tools for tool"},
-		"Linux":                               {"linux", "Linux", "ubuntu", "debian", "centos", "redhat", "fedora", "suse", "arch", "manjaro", "mint", "elementary", "popos", "kali", "raspbian", "raspberrypi", "raspberry pi", "raspberry", "pi", "linux kernel", "linux distributions", "linux distros", "linux desktop", "linux server", "linux laptop", "linux workstation", "linux desktop environment", "linux window manager", "linux shell", "linux terminal", "linux command line", "linux bash", "linux zsh", "linux fish", "linux ksh", "linux csh", "linux tcsh", "linux sh", "linux scripting", "linux programming", "linux development", "linux administration", "linux operations", "linux management", "linux monitoring", "linux scaling", "linux optimization", "linux performance", "linux reliability", "linux availability", "linux fault tolerance", "linux disaster recovery", "linux backup", "linux restore", "linux security", "linux compliance", "linux audits", "linux reviews", "linux ratings", "linux rankings", "linux awards", "linux recognition", "linux certifications", "linux badges", "linux labels", "linux tags", "linux categories", "linux topics", "linux subjects", "linux areas", "linux domains", "linux fields", "linux industries", "linux sectors", "linux verticals", "linux markets", "linux audiences", "linux users", "linux developers", "linux architects", "linux engineers", "linux administrators", "linux operators", "linux managers", "linux directors", "linux leads", "linux officers", "linux coordinators", "linux specialists", "linux consultants", "linux advisors", "linux partners", "linux vendors", "linux customers", "linux clients", "linux consumers", "linux producers", "linux providers", "linux services", "linux solutions", "linux products", "linux offerings", "linux features", "linux capabilities", "linux integrations", "linux extensions", "linux plugins", "linux modules", "linux packages", "linux dependencies", "linux security", "linux compliance", "linux audits", "linux reviews", "linux ratings", "linux rankings", "linux awards", "linux recognition", "linux certifications", "linux badges", "linux labels", "linux tags", "linux categories"},
-		"Virtualisation":                      {"virtualisation", "virtualization", "vm", "vmware", "virtualbox", "hypervisor", "kvm", "xen", "qemu", "VMware", "Proxmox", "proxmox", "esxi", "vcenter", "vSphere", "vSAN", "vRealize", "vCloud", "vCloud Director", "vCloud Suite", "vCloud Air", "vCloud Hybrid Service", "vCloud Connector", "vCloud Networking and Security", "vCloud Automation Center", "vCloud Application Director", "vCloud Operations Management Suite", "vCloud Suite SDK", "Hyperv", "hyperv", "hyper-v"},
-	}
-
-	// Categorize videos
-	categorizedVideos := categorizeVideos(videos, categoryKeywords)
-
-	// Save categorized videos to a new JSON file
-	if err := saveCategorizedVideos("categorized_videos.json", categorizedVideos); err != nil {
+	// Deleted, private and blocked videos can't be added to playlists
+	if unavailable := markUnavailable(videos, *region); len(unavailable) > 0 {
+		printUnavailable(unavailable)
+		if err := saveVideos("unavailable_videos.json", unavailable); err != nil {
+			log.Fatalf("Error saving unavailable videos: %v", err)
+		}
+	}
+
+	// Categorize videos, applying any manual overrides
+	overrides, err := lib.overrides()
+	if err != nil {
+		log.Fatalf("Error reading overrides from library: %v", err)
+	}
+
+	categorizedVideos := categorizeVideos(videos, rules, overrides)
+	printCategoryCounts(categorizedVideos, len(videos))
+	printIgnored(videos, rules, overrides)
+	if err := lib.saveCategories(categorizedVideos); err != nil {
+		log.Fatalf("Error saving categories to library: %v", err)
+	}
+
+	// Save categorized videos in each requested format
+	run := reportRun{GeneratedAt: now(), RulesFile: *rulesPath, Library: *libraryPath, Scrapes: scrapeFiles}
+	if run.RulesSHA256, err = fileSHA256(*rulesPath); err != nil {
+		log.Fatalf("Error hashing rules: %v", err)
+	}
+	if err := saveCategorizedVideos("categorized_videos", formats, categorizedReport{Run: run, Categories: categorizedVideos, Parents: rules.parents()}); err != nil {
 		log.Fatalf("Error saving categorized videos: %v", err)
 	}
 
-	fmt.Println("Categorized videos saved to categorized_videos.json")
+	// Fill the duration based smart playlists
+	smartPlaylists := buildSmartPlaylists(rules, categorizedVideos)
+	for _, smart := range smartPlaylists {
+		fmt.Printf("Smart playlist: %s, Number of Videos: %d\n", smart.Category, len(smart.Videos))
+	}
 
-	// Authenticate with YouTube Data API
-	client, err := getClient("credentials.json")
+	// Work out which playlists need creating, which videos are new and which
+	// have moved out
+	plans, err := planPlaylistSync(lib, rules, videos, categorizedVideos, smartPlaylists)
 	if err != nil {
-		log.Fatalf("Error getting YouTube client: %v", err)
+		log.Fatalf("Error planning playlist sync: %v", err)
+	}
+	if *dryRun {
+		printSyncPlan(plans)
+		return
 	}
 
-	service, err := youtube.NewService(context.Background(), option.WithHTTPClient(client))
+	if service == nil {
+		service = newYouTubeService()
+	}
+
+	// Create playlists for each category and add videos, recording the run in the library
+	syncID, err := lib.startSync()
 	if err != nil {
-		log.Fatalf("Error creating YouTube service: %v", err)
+		log.Fatalf("Error recording sync: %v", err)
 	}
 
-	// Create playlists for each category and add videos
-	for category := range categoryKeywords {
-		if err := createYouTubePlaylist(service, category, categorizedVideos); err != nil {
-			log.Fatalf("Error creating YouTube playlist for category %s: %v", category, err)
+	added := 0
+	for _, plan := range plans {
+		n, err := createYouTubePlaylist(service, lib, plan)
+		added += n
+		if err != nil {
+			lib.finishSync(syncID, added, err)
+			log.Fatalf("Error creating YouTube playlist for category %s: %v", plan.Category, err)
 		}
 	}
+	if err := lib.finishSync(syncID, added, nil); err != nil {
+		log.Fatalf("Error recording sync: %v", err)
+	}
 
 	fmt.Println("YouTube playlists created for each category")
 }
 
+// newYouTubeService authenticates with the YouTube Data API
+func newYouTubeService() *youtube.Service {
+	client, err := getClient("credentials.json")
+	if err != nil {
+		log.Fatalf("Error getting YouTube client: %v", err)
+	}
+
+	service, err := youtube.NewService(context.Background(), option.WithHTTPClient(client))
+	if err != nil {
+		log.Fatalf("Error creating YouTube service: %v", err)
+	}
+	return service
+}
+
 // readScrapeJSON reads and parses the scrape.json file
 func readScrapeJSON(filename string) ([]Video, error) {
 	file, err := os.Open(filename)
@@ -106,66 +288,140 @@ func readScrapeJSON(filename string) ([]Video, error) {
 	return videos, nil
 }
 
-// categorizeVideos categorizes videos based on their titles using a map of category keywords
-func categorizeVideos(videos []Video, categoryKeywords map[string][]string) []CategorizedVideos {
-	// Define the order of categories based on their specificity
-	categories := []string{
-		"Programming & Development",
-		"Cloud & Infrastructure",
-		"DevOps and CI/CD",
-		"Containers and Kubernetes",
-		"Data Management and Databases",
-		"Cloud-Native and Serverless",
-		"Security and DevSecOps",
-		"Open Source and Community",
-		"Storytelling and Career Development",
-		"AI and Emerging Technologies",
-		"Tools and Productivity",
-		"Linux",
-		"Virtualisation",
-	}
+// categorizeVideos categorizes videos by matching each category's keywords
+// against the weighted fields in the rules; the highest scoring category wins,
+// or its most specific matching child, and ties go to the category listed
+// first. Manual overrides (video ID to category) are applied after the rules;
+// videos overridden to excludeCategory are left out entirely, as are videos
+// the ignore rules match unless they have an override.
+func categorizeVideos(videos []Video, rules *Rules, overrides map[string]string) []CategorizedVideos {
+	categories := rules.categoryNames()
 
 	categorized := make([]CategorizedVideos, len(categories)+1)
+	index := make(map[string]int)
 	for i, category := range categories {
 		categorized[i] = CategorizedVideos{Category: category}
+		index[category] = i
+	}
+	categorized[len(categories)] = CategorizedVideos{Category: otherCategory}
+	index[otherCategory] = len(categories)
+
+	// All videos are embedded at once, so an endpoint is called in batches. If
+	// that fails the keywords still work.
+	var nearest []semanticMatch
+	if rules.semantic != nil {
+		var err error
+		nearest, err = rules.semantic.nearest(videos, rules.Categories)
+		if err != nil {
+			fmt.Printf("Error embedding videos, using keywords only: %v\n", err)
+		}
 	}
-	categorized[len(categories)] = CategorizedVideos{Category: "Other"}
 
-	for _, video := range videos {
-		found := false
-		for i, category := range categories {
-			for _, keyword := range categoryKeywords[category] {
-				if strings.Contains(strings.ToLower(video.Title), strings.ToLower(keyword)) {
-					categorized[i].Videos = append(categorized[i].Videos, video)
-					found = true
-					break
+	for v, video := range videos {
+		if _, overridden := overrides[videoKey(video)]; !overridden && rules.Ignore.reason(video) != "" {
+			continue
+		}
+
+		video.Language = detectLanguage(video)
+		video.Scores = nil
+		matchedBy := make(map[string][]string)
+		for _, rule := range rules.Categories {
+			score, matched := rule.score(video, rules.Fields)
+			if score > 0 {
+				if video.Scores == nil {
+					video.Scores = make(map[string]float64)
 				}
+				video.Scores[rule.Name] = score
+				matchedBy[rule.Name] = matched
 			}
-			if found {
-				break
+		}
+		category := rules.pickCategory(video.Scores)
+		video.Score, video.MatchedKeywords = video.Scores[category], matchedBy[category]
+
+		// The classifier fills in for (or, as primary, overrules) the keywords
+		// when it is confident enough
+		video.Prediction, video.PredictionConfidence = "", 0
+		if rules.model != nil {
+			video.Prediction, video.PredictionConfidence = rules.model.predict(video, rules.Fields)
+			confident := video.PredictionConfidence >= rules.Classifier.minConfidence()
+			known := video.Prediction == otherCategory || containsString(categories, video.Prediction)
+			if confident && known && (category == otherCategory || rules.Classifier.Mode == "primary") {
+				category = video.Prediction
+				video.Score, video.MatchedKeywords = video.Scores[category], matchedBy[category]
 			}
 		}
-		if !found {
-			categorized[len(categories)].Videos = append(categorized[len(categories)].Videos, video)
+
+		// So does the closest category by meaning, when it is close enough
+		video.SemanticCategory, video.Similarity = "", 0
+		if nearest != nil {
+			video.SemanticCategory, video.Similarity = nearest[v].Category, nearest[v].Similarity
+			similar := video.SemanticCategory != "" && video.Similarity >= rules.Embeddings.minSimilarity()
+			if similar && (category == otherCategory || rules.Embeddings.Mode == "primary") {
+				category = video.SemanticCategory
+				video.Score, video.MatchedKeywords = video.Scores[category], matchedBy[category]
+			}
 		}
-	}
 
-	// Debug: Print categorized videos
-	for _, catVideos := range categorized {
-		fmt.Printf("Category: %s, Number of Videos: %d\n", catVideos.Category, len(catVideos.Videos))
+		// Videos in another language can go to a playlist of their own
+		if name := rules.Languages.category(video.Language); name != "" {
+			category = name
+			video.Score, video.MatchedKeywords = 0, nil
+		}
+
+		// Manual overrides beat the keyword rules
+		if override, ok := overrides[videoKey(video)]; ok {
+			video.Override = override
+			if override == excludeCategory {
+				continue
+			}
+			category = override
+		}
+
+		i, ok := index[category]
+		if !ok {
+			i = len(categorized)
+			index[category] = i
+			categorized = append(categorized, CategorizedVideos{Category: category})
+		}
+		categorized[i].Videos = append(categorized[i].Videos, video)
 	}
 
 	return categorized
 }
 
-// saveCategorizedVideos saves the categorized videos to a JSON file
-func saveCategorizedVideos(filename string, categorizedVideos []CategorizedVideos) error {
-	bytes, err := json.MarshalIndent(categorizedVideos, "", "  ")
-	if err != nil {
-		return err
+// printCategoryCounts prints how many videos ended up in each category and how many were excluded
+func printCategoryCounts(categorizedVideos []CategorizedVideos, total int) {
+	categorizedCount := 0
+	for _, catVideos := range categorizedVideos {
+		fmt.Printf("Category: %s, Number of Videos: %d\n", catVideos.Category, len(catVideos.Videos))
+		categorizedCount += len(catVideos.Videos)
+	}
+	if excluded := total - categorizedCount; excluded > 0 {
+		fmt.Printf("Excluded by override or ignore rules: %d\n", excluded)
 	}
+}
 
-	return ioutil.WriteFile(filename, bytes, 0644)
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// saveCategorizedVideos saves the report to base plus the extension of each
+// format, e.g. categorized_videos.json
+func saveCategorizedVideos(base string, formats []string, report categorizedReport) error {
+	for _, format := range formats {
+		filename, err := saveReport(base, format, report)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Categorized videos saved to %s\n", filename)
+	}
+	return nil
 }
 
 // getClient uses a Context and Config to retrieve a Token
@@ -231,62 +487,129 @@ func saveToken(path string, token *oauth2.Token) {
 	json.NewEncoder(f).Encode(token)
 }
 
-// createYouTubePlaylist creates a playlist for a given category and adds videos to it
-func createYouTubePlaylist(service *youtube.Service, category string, categorizedVideos []CategorizedVideos) error {
-	for _, catVideos := range categorizedVideos {
-		if catVideos.Category == category {
-			// Create playlist
-			playlist := &youtube.Playlist{
-				Snippet: &youtube.PlaylistSnippet{
-					Title:       category + " Playlist",
-					Description: "A playlist of " + category + " videos",
-				},
-				Status: &youtube.PlaylistStatus{
-					PrivacyStatus: "private",
+// createYouTubePlaylist creates the playlist for a category if it doesn't exist
+// yet, removes the videos planned for removal and adds the planned videos to
+// it. Every playlist and video is recorded in the library as it goes, so a
+// sync interrupted by quota errors can simply be re-run. It returns the number
+// of videos added.
+func createYouTubePlaylist(service *youtube.Service, lib *Library, plan playlistPlan) (int, error) {
+	added := 0
+	playlistID := plan.PlaylistID
+
+	if playlistID != "" && len(plan.Remove) > 0 {
+		if err := removeFromYouTubePlaylist(service, lib, playlistID, plan.Remove); err != nil {
+			return added, err
+		}
+	}
+
+	if playlistID == "" {
+		// Create playlist
+		playlist := &youtube.Playlist{
+			Snippet: &youtube.PlaylistSnippet{
+				Title:       plan.Title,
+				Description: plan.Description,
+			},
+			Status: &youtube.PlaylistStatus{
+				PrivacyStatus: "private",
+			},
+		}
+
+		playlistResponse, err := service.Playlists.Insert([]string{"snippet", "status"}, playlist).Do()
+		if err != nil {
+			return added, fmt.Errorf("error creating playlist: %v", err)
+		}
+		playlistID = playlistResponse.Id
+
+		if err := lib.savePlaylist(plan.Category, playlistID, plan.Title); err != nil {
+			return added, err
+		}
+		fmt.Printf("Playlist created for category %s: %s\n", plan.Category, playlistID)
+	}
+
+	// Add videos to playlist
+	for _, video := range plan.Add {
+		fmt.Printf("Adding video to playlist: %s (ID: %s)\n", video.Title, video.ID)
+
+		playlistItem := &youtube.PlaylistItem{
+			Snippet: &youtube.PlaylistItemSnippet{
+				PlaylistId: playlistID,
+				ResourceId: &youtube.ResourceId{
+					Kind:    "youtube#video",
+					VideoId: video.ID,
 				},
-			}
+			},
+		}
 
-			playlistResponse, err := service.Playlists.Insert([]string{"snippet", "status"}, playlist).Do()
-			if err != nil {
-				return fmt.Errorf("error creating playlist: %v", err)
-			}
+		_, err := service.PlaylistItems.Insert([]string{"snippet"}, playlistItem).Do()
+		if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == http.StatusNotFound {
+			// Deleted since the last enrich, skip it rather than killing the run
+			fmt.Printf("Skipping unavailable video: %s (ID: %s)\n", video.Title, video.ID)
+			continue
+		}
+		if err != nil {
+			return added, fmt.Errorf("error adding video to playlist: %v", err)
+		}
+		if err := lib.savePlaylistItem(playlistID, video.ID); err != nil {
+			return added, err
+		}
+		added++
+	}
 
-			// Add videos to playlist
-			for _, video := range catVideos.Videos {
-				videoID := extractVideoID(video.Link)
-				fmt.Printf("Adding video to playlist: %s (ID: %s)\n", video.Title, videoID)
-
-				playlistItem := &youtube.PlaylistItem{
-					Snippet: &youtube.PlaylistItemSnippet{
-						PlaylistId: playlistResponse.Id,
-						ResourceId: &youtube.ResourceId{
-							Kind:    "youtube#video",
-							VideoId: videoID,
-						},
-					},
-				}
+	fmt.Printf("Playlist synced for category %s: %s\n", plan.Category, playlistID)
+	return added, nil
+}
 
-				_, err := service.PlaylistItems.Insert([]string{"snippet"}, playlistItem).Do()
-				if err != nil {
-					return fmt.Errorf("error adding video to playlist: %v", err)
-				}
-			}
+// listPlaylistItems returns every item in a playlist. Callers delete items
+// only once the whole list is read, as deleting while paging shifts later
+// items onto pages already read.
+func listPlaylistItems(service *youtube.Service, playlistID string) ([]*youtube.PlaylistItem, error) {
+	var items []*youtube.PlaylistItem
+	err := service.PlaylistItems.List([]string{"snippet"}).PlaylistId(playlistID).MaxResults(50).Pages(context.Background(), func(response *youtube.PlaylistItemListResponse) error {
+		items = append(items, response.Items...)
+		return nil
+	})
+	return items, err
+}
+
+// removeFromYouTubePlaylist deletes the given videos from a playlist. The
+// playlist is listed to find their playlist item IDs, which the library
+// doesn't keep.
+func removeFromYouTubePlaylist(service *youtube.Service, lib *Library, playlistID string, videos []Video) error {
+	remove := make(map[string]bool)
+	for _, video := range videos {
+		remove[video.ID] = true
+	}
+
+	items, err := listPlaylistItems(service, playlistID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		videoID := item.Snippet.ResourceId.VideoId
+		if !remove[videoID] {
+			continue
+		}
+		fmt.Printf("Removing video from playlist: %s (ID: %s)\n", item.Snippet.Title, videoID)
+		if err := service.PlaylistItems.Delete(item.Id).Do(); err != nil {
+			return fmt.Errorf("error removing video from playlist: %v", err)
+		}
+	}
 
-			fmt.Printf("Playlist created for category %s: %s\n", category, playlistResponse.Id)
-			break
+	// Forget them even if they had already gone from YouTube
+	for videoID := range remove {
+		if err := lib.deletePlaylistItem(playlistID, videoID); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// extractVideoID extracts the video ID from a YouTube link
+// extractVideoID extracts the video ID from a YouTube link, or returns "" if
+// the link has none; callers report those videos their own way
 func extractVideoID(link string) string {
 	parts := strings.Split(link, "v=")
 	if len(parts) > 1 {
-		videoID := strings.Split(parts[1], "&")[0]
-		fmt.Printf("Extracted video ID: %s from link: %s\n", videoID, link)
-		return videoID
+		return strings.Split(parts[1], "&")[0]
 	}
-	fmt.Printf("Failed to extract video ID from link: %s\n", link)
 	return ""
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTypos is the most typos a keyword can be allowed
+const maxTypos = 3
+
+// typoLetters is how long a keyword must be for each typo it's allowed, so
+// short keywords like "docker" never match "locker"
+const typoLetters = 8
+
+// Matching loosens how keywords match video text. Each option is applied to
+// the keyword and the text alike, so "Containerisation" matches the keyword
+// "containerization" once spelling is on.
+type Matching struct {
+	// Stem reduces English words to their stem, so "databases",
+	// "virtualized" and "caching" match "database", "virtualization" and
+	// "cache"
+	Stem bool `json:"stem,omitempty"`
+
+	// Spelling folds British spellings into American, e.g. "colour" into
+	// "color" and "virtualisation" into "virtualization"
+	Spelling bool `json:"spelling,omitempty"`
+
+	// Diacritics drops accents, e.g. "programación" becomes "programacion"
+	Diacritics bool `json:"diacritics,omitempty"`
+
+	// Typos lets a keyword match with up to this many letters added, removed
+	// or changed, one for every typoLetters letters of the keyword
+	Typos int `json:"typos,omitempty"`
+}
+
+// validate checks the number of typos is sensible
+func (m *Matching) validate() error {
+	if m != nil && (m.Typos < 0 || m.Typos > maxTypos) {
+		return fmt.Errorf("typos must be between 0 and %d", maxTypos)
+	}
+	return nil
+}
+
+// orMatching returns the first matching that is set, or exact matching
+func orMatching(matchings ...*Matching) Matching {
+	for _, matching := range matchings {
+		if matching != nil {
+			return *matching
+		}
+	}
+	return Matching{}
+}
+
+// wordPattern matches the words normalize folds and typos are counted in;
+// everything between them, like the "++" in "c++", is left alone
+var wordPattern = regexp.MustCompile(`[\pL\pN]+`)
+
+// normalize applies the matching's options to lower-case text
+func (m Matching) normalize(text string) string {
+	if m.Diacritics {
+		text = diacriticFolder.Replace(text)
+	}
+	if !m.Spelling && !m.Stem {
+		return text
+	}
+	return wordPattern.ReplaceAllStringFunc(text, func(word string) string {
+		if m.Spelling {
+			word = americanSpelling(word)
+		}
+		if m.Stem {
+			word = stem(word)
+		}
+		return word
+	})
+}
+
+// contains reports whether the normalized keyword is in the normalized text,
+// allowing the matching's typos for a keyword of letters letters
+func (m Matching) contains(text, keyword string, letters int) bool {
+	if strings.Contains(text, keyword) {
+		return true
+	}
+	typos := min(m.Typos, letters/typoLetters)
+	if typos == 0 {
+		return false
+	}
+
+	// Compare the keyword with every run of as many words in the text
+	keywordWords := wordPattern.FindAllString(keyword, -1)
+	textWords := wordPattern.FindAllString(text, -1)
+	want := strings.Join(keywordWords, " ")
+	for i := 0; i+len(keywordWords) <= len(textWords) && len(keywordWords) > 0; i++ {
+		if withinEdits(want, strings.Join(textWords[i:i+len(keywordWords)], " "), typos) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinEdits reports whether a can be turned into b with at most max
+// letters added, removed or changed (the Levenshtein distance)
+func withinEdits(a, b string, max int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra)-len(rb) > max || len(rb)-len(ra) > max {
+		return false
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		best := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			best = min(best, current[j])
+		}
+		if best > max {
+			return false
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)] <= max
+}
+
+// keywordLetters counts the letters and digits of a keyword, which decides how
+// many typos it can have
+func keywordLetters(keyword string) int {
+	letters := 0
+	for _, word := range wordPattern.FindAllString(keyword, -1) {
+		letters += utf8.RuneCountInString(word)
+	}
+	return letters
+}
+
+// diacriticFolder replaces accented lower-case letters with plain ones
+var diacriticFolder = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ā", "a", "ą", "a", "æ", "ae",
+	"ç", "c", "ć", "c", "č", "c", "ď", "d", "đ", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ē", "e", "ę", "e", "ě", "e", "ğ", "g",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ī", "i", "ı", "i", "ł", "l", "ľ", "l",
+	"ñ", "n", "ń", "n", "ň", "n", "ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "ō", "o", "ő", "o", "œ", "oe",
+	"ř", "r", "ś", "s", "š", "s", "ş", "s", "ß", "ss", "ť", "t", "ţ", "t",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ū", "u", "ů", "u", "ű", "u",
+	"ý", "y", "ÿ", "y", "ź", "z", "ż", "z", "ž", "z",
+)
+
+// britishWords are British spellings that don't follow a pattern
+var britishWords = map[string]string{
+	"aeroplane": "airplane", "ageing": "aging", "aluminium": "aluminum", "cheque": "check",
+	"cosy": "cozy", "defence": "defense", "grey": "gray", "judgement": "judgment",
+	"licence": "license", "manoeuvre": "maneuver", "mould": "mold", "offence": "offense",
+	"plough": "plow", "programme": "program", "programmes": "programs", "sceptic": "skeptic",
+	"sceptical": "skeptical", "tyre": "tire", "tyres": "tires",
+}
+
+// britishEnding is a British word ending and its American spelling, used when
+// at least minStem letters come before it, so "colour" is folded but "four"
+// isn't
+type britishEnding struct {
+	British, American string
+	MinStem           int
+}
+
+// britishEndings are tried in order, longer endings first
+var britishEndings = []britishEnding{
+	{"isations", "izations", 3}, {"isation", "ization", 3},
+	{"isers", "izers", 4}, {"ising", "izing", 4}, {"ised", "ized", 4}, {"iser", "izer", 4}, {"ises", "izes", 4}, {"ise", "ize", 4},
+	{"ysing", "yzing", 3}, {"ysed", "yzed", 3}, {"yse", "yze", 3},
+	{"ellers", "elers", 3}, {"elling", "eling", 3}, {"elled", "eled", 3}, {"eller", "eler", 3},
+	{"iours", "iors", 2}, {"iour", "ior", 2}, {"ours", "ors", 3}, {"our", "or", 3},
+	{"tres", "ters", 2}, {"tre", "ter", 2}, {"bres", "bers", 2}, {"bre", "ber", 2},
+	{"ogues", "ogs", 3}, {"ogue", "og", 3},
+}
+
+// americanSpelling folds a lower-case British word into its American spelling
+func americanSpelling(word string) string {
+	if american, ok := britishWords[word]; ok {
+		return american
+	}
+	for _, ending := range britishEndings {
+		if strings.HasSuffix(word, ending.British) && len(word)-len(ending.British) >= ending.MinStem {
+			return strings.TrimSuffix(word, ending.British) + ending.American
+		}
+	}
+	return word
+}
+
+// stemEndings are the word endings stem removes after plurals, longer
+// endings first
+var stemEndings = []string{"ization", "isation", "ation", "ingly", "edly", "ing", "ed"}
+
+// stem reduces a lower-case English word to a stem shared with its other
+// forms. It is a light version of Porter's algorithm: plurals, "-ing",
+// "-ed", "-ation", "-ize", "-ate" and a final "e" are removed, and the stem
+// must keep a vowel, so "string" and "bring" are left alone.
+func stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	// Plurals
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "xes"):
+		word = strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = strings.TrimSuffix(word, "s")
+	}
+
+	for _, ending := range stemEndings {
+		base := strings.TrimSuffix(word, ending)
+		if base == word || len(base) < 3 || !hasVowel(base) {
+			continue
+		}
+		word = base
+		if ending == "ing" || ending == "ed" || ending == "ingly" || ending == "edly" {
+			// "automated" becomes "automate", "running" becomes "run"
+			switch last := len(word) - 1; {
+			case strings.HasSuffix(word, "at"), strings.HasSuffix(word, "iz"), strings.HasSuffix(word, "bl"):
+				word += "e"
+			case word[last] == word[last-1] && !strings.ContainsRune("aeiouylsz", rune(word[last])):
+				word = word[:last]
+			}
+		}
+		break
+	}
+
+	for _, ending := range []string{"ize", "ate", "e"} {
+		if base := strings.TrimSuffix(word, ending); base != word && len(base) >= 3 && hasVowel(base) {
+			return base
+		}
+	}
+	return word
+}
+
+// hasVowel reports whether a word has a vowel, counting "y"
+func hasVowel(word string) bool {
+	return strings.ContainsAny(word, "aeiouy")
+}
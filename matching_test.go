@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct{ word, want string }{
+		{"databases", "databas"},
+		{"database", "databas"},
+		{"caching", "cach"},
+		{"cache", "cach"},
+		{"virtualization", "virtual"},
+		{"virtualized", "virtual"},
+		{"automated", "autom"},
+		{"automation", "autom"},
+		{"running", "run"},
+		{"classes", "class"},
+		{"policies", "policy"},
+		{"string", "string"},
+		{"bring", "bring"},
+		{"kubernetes", "kubernet"},
+		{"go", "go"},
+		{"aws", "aws"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestWithinEdits(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want bool
+	}{
+		{"kubernetes", "kubernetes", 0, true},
+		{"kubernetes", "kubernets", 1, true},
+		{"kubernetes", "kubrenetes", 1, false},
+		{"kubernetes", "kubrenetes", 2, true},
+		{"database", "databse", 1, true},
+		{"docker", "locker", 0, false},
+		{"docker", "locker", 1, true},
+		{"terraform", "terra", 1, false},
+		{"programación", "programacion", 1, true},
+		{"", "ab", 2, true},
+	}
+	for _, tt := range tests {
+		if got := withinEdits(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("withinEdits(%q, %q, %d) = %v, want %v", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestAmericanSpelling(t *testing.T) {
+	tests := []struct{ word, want string }{
+		{"virtualisation", "virtualization"},
+		{"containerised", "containerized"},
+		{"optimising", "optimizing"},
+		{"analyse", "analyze"},
+		{"colour", "color"},
+		{"behaviour", "behavior"},
+		{"modelling", "modeling"},
+		{"centre", "center"},
+		{"catalogue", "catalog"},
+		{"grey", "gray"},
+		{"programme", "program"},
+		{"four", "four"},
+		{"rise", "rise"},
+		{"kubernetes", "kubernetes"},
+	}
+	for _, tt := range tests {
+		if got := americanSpelling(tt.word); got != tt.want {
+			t.Errorf("americanSpelling(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestMatchingContains(t *testing.T) {
+	loose := Matching{Stem: true, Spelling: true, Diacritics: true, Typos: 1}
+	tests := []struct {
+		matching      Matching
+		text, keyword string
+		want          bool
+	}{
+		{Matching{}, "kubernets networking", "kubernetes", false},
+		{loose, "kubernets networking", "kubernetes", true},
+		{loose, "docker vs locker", "locker", true},
+		{loose, "a locker room", "docker", false},
+		{loose, "containerised apps", "containerization", true},
+		{loose, "introducción a la programacion", "programación", true},
+	}
+	for _, tt := range tests {
+		text, keyword := tt.matching.normalize(tt.text), tt.matching.normalize(tt.keyword)
+		if got := tt.matching.contains(text, keyword, keywordLetters(tt.keyword)); got != tt.want {
+			t.Errorf("%+v: contains(%q, %q) = %v, want %v", tt.matching, tt.text, tt.keyword, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// excludeCategory is the override that keeps a video out of every category and playlist
+const excludeCategory = "exclude"
+
+// overrides returns the manual category overrides keyed by video ID
+func (l *Library) overrides() (map[string]string, error) {
+	rows, err := l.db.Query("SELECT video_id, category FROM overrides")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make(map[string]string)
+	for rows.Next() {
+		var videoID, category string
+		if err := rows.Scan(&videoID, &category); err != nil {
+			return nil, err
+		}
+		overrides[videoID] = category
+	}
+	return overrides, rows.Err()
+}
+
+// setOverride forces a video into a category (or excludeCategory) regardless of the rules
+func (l *Library) setOverride(videoID, category string) error {
+	_, err := l.db.Exec("INSERT OR REPLACE INTO overrides (video_id, category, updated_at) VALUES (?, ?, ?)",
+		videoID, category, now())
+	return err
+}
+
+// clearOverride removes a video's override so the rules decide its category again
+func (l *Library) clearOverride(videoID string) (bool, error) {
+	result, err := l.db.Exec("DELETE FROM overrides WHERE video_id = ?", videoID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// validOverride reports whether category can be used as an override: a
+// category, Other, exclude or one of the per-language categories
+func validOverride(rules *Rules, category string) bool {
+	return category == excludeCategory || category == otherCategory || containsString(rules.categoryNames(), category) ||
+		rules.Languages.isCategory(category)
+}
+
+// overrideVideoID accepts either a bare video ID or a YouTube link
+func overrideVideoID(arg string) string {
+	if strings.Contains(arg, "v=") {
+		return extractVideoID(arg)
+	}
+	return arg
+}
+
+// runOverride implements `override set|clear|list|import`
+func runOverride(args []string) {
+	flags := flag.NewFlagSet("override", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:")
+		fmt.Fprintln(os.Stderr, "  go run . override set <video ID or link> <category|exclude>")
+		fmt.Fprintln(os.Stderr, "  go run . override clear <video ID or link>")
+		fmt.Fprintln(os.Stderr, "  go run . override list")
+		fmt.Fprintln(os.Stderr, "  go run . override import <categorized_videos.csv|.tsv|.json>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	switch flags.Arg(0) {
+	case "set":
+		if flags.NArg() != 3 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		videoID, category := overrideVideoID(flags.Arg(1)), flags.Arg(2)
+		if !validOverride(rules, category) {
+			log.Fatalf("Unknown category %q, expected one of: %s, %s or %s", category, strings.Join(rules.categoryNames(), ", "), otherCategory, excludeCategory)
+		}
+		if err := lib.setOverride(videoID, category); err != nil {
+			log.Fatalf("Error saving override: %v", err)
+		}
+		fmt.Printf("Override set: %s -> %s\n", videoID, category)
+
+	case "clear":
+		if flags.NArg() != 2 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		videoID := overrideVideoID(flags.Arg(1))
+		cleared, err := lib.clearOverride(videoID)
+		if err != nil {
+			log.Fatalf("Error clearing override: %v", err)
+		}
+		if !cleared {
+			fmt.Printf("No override for %s\n", videoID)
+			return
+		}
+		fmt.Printf("Override cleared: %s\n", videoID)
+
+	case "list":
+		overrides, err := lib.overrides()
+		if err != nil {
+			log.Fatalf("Error reading overrides: %v", err)
+		}
+		ids := make([]string, 0, len(overrides))
+		for id := range overrides {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Printf("%s -> %s\n", id, overrides[id])
+		}
+		fmt.Printf("%d overrides\n", len(overrides))
+
+	case "import":
+		if flags.NArg() != 2 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		result, err := importOverrides(lib, rules, flags.Arg(1))
+		if err != nil {
+			log.Fatalf("Error importing %s: %v", flags.Arg(1), err)
+		}
+		for _, skipped := range result.Skipped {
+			fmt.Printf("Skipped %s\n", skipped)
+		}
+		fmt.Printf("Overrides set: %d, cleared: %d, unchanged: %d, skipped: %d\n", result.Set, result.Cleared, result.Unchanged, len(result.Skipped))
+
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
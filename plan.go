@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// todayPlaylist is the rolling playlist synced from the first day of a watch plan
+const todayPlaylist = "Today"
+
+// planDateLayout is how days in a watch plan are written
+const planDateLayout = "2006-01-02"
+
+// plannedVideo is a video scheduled on a day of the watch plan
+type plannedVideo struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Link            string `json:"link"`
+	Channel         string `json:"channel,omitempty"`
+	Category        string `json:"category"`
+	DurationSeconds int    `json:"durationSeconds"`
+}
+
+// watchPlanDay is the queue of videos for one day
+type watchPlanDay struct {
+	Date    string         `json:"date"`
+	Seconds int            `json:"seconds"`
+	Videos  []plannedVideo `json:"videos"`
+}
+
+// watchPlan is a queue of videos for each day of the coming days
+type watchPlan struct {
+	Generated   string         `json:"generated"`
+	DailyBudget string         `json:"dailyBudget"`
+	DayStart    string         `json:"dayStart"`
+	Prefer      string         `json:"prefer"`
+	Days        []watchPlanDay `json:"days"`
+
+	// Unplanned counts videos left over once every day is full
+	Unplanned int `json:"unplanned"`
+	// NoDuration and TooLong count videos that can never be planned
+	NoDuration int `json:"noDuration"`
+	TooLong    int `json:"tooLong"`
+}
+
+// planQueue is the videos still to be planned in one category
+type planQueue struct {
+	category string
+	weight   float64
+	planned  int
+	videos   []Video
+}
+
+// parseCategoryWeights parses weights such as "Kubernetes=2,Other=0". Every
+// category not listed gets a weight of 1 and a weight of 0 leaves it out.
+func parseCategoryWeights(value string, rules *Rules) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	if strings.TrimSpace(value) == "" {
+		return weights, nil
+	}
+	known := append(rules.categoryNames(), otherCategory)
+	for _, pair := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("bad weight %q, expected category=weight", pair)
+		}
+		if !containsString(known, name) {
+			return nil, fmt.Errorf("unknown category %q", name)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("bad weight for %q: %s", name, weight)
+		}
+		weights[name] = w
+	}
+	return weights, nil
+}
+
+// sortForPlan orders a category's videos so the preferred ones are planned
+// first: "oldest" is the longest in Watch Later, "relevant" the highest
+// rule score
+func sortForPlan(videos []Video, prefer string) {
+	sort.SliceStable(videos, func(i, j int) bool {
+		if prefer == "relevant" {
+			return videos[i].Score > videos[j].Score
+		}
+		return videos[i].FirstSeen < videos[j].FirstSeen
+	})
+}
+
+// buildWatchPlan packs videos into days of at most daily watch time. Each
+// slot goes to the category with the least planned time for its weight that
+// still has a video fitting in what's left of the day, so heavier categories
+// get proportionally more time; within a category the first preferred video
+// that fits is taken.
+func buildWatchPlan(categorizedVideos []CategorizedVideos, weights map[string]float64, daily time.Duration, start time.Time, dayStart string, days int, prefer string) watchPlan {
+	budget := int(daily.Seconds())
+	plan := watchPlan{
+		Generated:   now(),
+		DailyBudget: daily.String(),
+		DayStart:    dayStart,
+		Prefer:      prefer,
+		Days:        []watchPlanDay{},
+	}
+
+	var queues []*planQueue
+	for _, catVideos := range categorizedVideos {
+		weight, ok := weights[catVideos.Category]
+		if !ok {
+			weight = 1
+		}
+		if weight == 0 {
+			continue
+		}
+
+		queue := &planQueue{category: catVideos.Category, weight: weight}
+		for _, video := range catVideos.Videos {
+			switch {
+			case video.Unavailable != "":
+				continue
+			case video.DurationSeconds <= 0:
+				plan.NoDuration++
+			case video.DurationSeconds > budget:
+				plan.TooLong++
+			default:
+				queue.videos = append(queue.videos, video)
+			}
+		}
+		sortForPlan(queue.videos, prefer)
+		queues = append(queues, queue)
+	}
+
+	for day := 0; day < days; day++ {
+		planDay := watchPlanDay{Date: start.AddDate(0, 0, day).Format(planDateLayout), Videos: []plannedVideo{}}
+		for {
+			var best *planQueue
+			bestIndex := -1
+			for _, queue := range queues {
+				if best != nil && float64(queue.planned)/queue.weight >= float64(best.planned)/best.weight {
+					continue
+				}
+				for i, video := range queue.videos {
+					if planDay.Seconds+video.DurationSeconds <= budget {
+						best, bestIndex = queue, i
+						break
+					}
+				}
+			}
+			if best == nil {
+				break
+			}
+
+			video := best.videos[bestIndex]
+			best.videos = append(best.videos[:bestIndex], best.videos[bestIndex+1:]...)
+			best.planned += video.DurationSeconds
+			planDay.Seconds += video.DurationSeconds
+			planDay.Videos = append(planDay.Videos, plannedVideo{
+				ID:              video.ID,
+				Title:           video.Title,
+				Link:            video.Link,
+				Channel:         video.Channel,
+				Category:        best.category,
+				DurationSeconds: video.DurationSeconds,
+			})
+		}
+		plan.Days = append(plan.Days, planDay)
+	}
+
+	for _, queue := range queues {
+		plan.Unplanned += len(queue.videos)
+	}
+	return plan
+}
+
+// markdownWatchPlan renders the plan as a Markdown checklist per day
+func markdownWatchPlan(plan watchPlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Watch plan\n\n%s a day, favouring the %s videos. Generated %s.\n", plan.DailyBudget, plan.Prefer, plan.Generated)
+	for _, day := range plan.Days {
+		date, _ := time.Parse(planDateLayout, day.Date)
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", date.Format("Monday 2 January 2006"), formatWatchTime(day.Seconds))
+		if len(day.Videos) == 0 {
+			b.WriteString("Nothing left to plan.\n")
+		}
+		for _, video := range day.Videos {
+			fmt.Fprintf(&b, "- [ ] [%s](%s) (%s, %s)\n", video.Title, video.Link, formatDuration(video.DurationSeconds), video.Category)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d videos left for later, %d with no known duration, %d longer than a day's budget.\n", plan.Unplanned, plan.NoDuration, plan.TooLong)
+	return b.String()
+}
+
+// saveWatchPlan writes the plan as Markdown, JSON or iCalendar; icsGroup is
+// passed on to icsWatchPlan
+func saveWatchPlan(filename, format string, plan watchPlan, icsGroup string) error {
+	var data []byte
+	switch format {
+	case "json":
+		var err error
+		if data, err = json.MarshalIndent(plan, "", "  "); err != nil {
+			return err
+		}
+	case "markdown":
+		data = []byte(markdownWatchPlan(plan))
+	case "ics":
+		calendar, err := icsWatchPlan(plan, icsGroup)
+		if err != nil {
+			return err
+		}
+		data = []byte(calendar)
+	default:
+		return fmt.Errorf("unknown format %q, expected markdown, json or ics", format)
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// syncTodayPlaylist makes the rolling Today playlist match the first day of
+// the plan: videos no longer planned for today are removed and new ones added
+func syncTodayPlaylist(service *youtube.Service, lib *Library, today watchPlanDay) (int, error) {
+	playlistID, err := lib.playlistID(todayPlaylist)
+	if err != nil {
+		return 0, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, video := range today.Videos {
+		wanted[video.ID] = true
+	}
+
+	present := make(map[string]bool)
+	if playlistID != "" {
+		items, err := listPlaylistItems(service, playlistID)
+		if err != nil {
+			return 0, err
+		}
+		for _, item := range items {
+			videoID := item.Snippet.ResourceId.VideoId
+			if wanted[videoID] && !present[videoID] {
+				present[videoID] = true
+				continue
+			}
+			fmt.Printf("Removing video from %s playlist: %s (ID: %s)\n", todayPlaylist, item.Snippet.Title, videoID)
+			if err := service.PlaylistItems.Delete(item.Id).Do(); err != nil {
+				return 0, fmt.Errorf("error removing video from playlist: %v", err)
+			}
+			if err := lib.deletePlaylistItem(playlistID, videoID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	plan := playlistPlan{
+		Category:    todayPlaylist,
+		Title:       todayPlaylist,
+		Description: "Today's videos from the watch plan",
+		PlaylistID:  playlistID,
+	}
+	for _, video := range today.Videos {
+		if !present[video.ID] {
+			plan.Add = append(plan.Add, Video{ID: video.ID, Title: video.Title, Link: video.Link})
+		}
+	}
+	return createYouTubePlaylist(service, lib, plan)
+}
+
+// runPlan implements the `plan` command
+func runPlan(args []string) {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	daily := flags.Duration("daily", time.Hour, "daily viewing budget")
+	days := flags.Int("days", 7, "number of days to plan")
+	startDate := flags.String("start", "", "first day of the plan as YYYY-MM-DD (default today)")
+	weightsFlag := flags.String("weights", "", "category weights, e.g. \"Kubernetes=2,Other=0\"; unlisted categories get 1")
+	prefer := flags.String("prefer", "oldest", "which videos to plan first: oldest or relevant")
+	dayStart := flags.String("day-start", "19:00", "time of day viewing starts, used for calendar events")
+	format := flags.String("format", "markdown", "output format: markdown, json or ics")
+	icsGroup := flags.String("ics-group", "video", "calendar events per video, or one per category per day with \"category\"")
+	output := flags.String("o", "", "file to write the plan to (default watch_plan.md, .json or .ics)")
+	syncToday := flags.Bool("sync-today", false, "sync the first day of the plan to a rolling \"Today\" playlist on YouTube")
+	flags.Parse(args)
+
+	if *prefer != "oldest" && *prefer != "relevant" {
+		log.Fatalf("Error: -prefer must be oldest or relevant, not %q", *prefer)
+	}
+	if *daily <= 0 || *days <= 0 {
+		log.Fatalf("Error: -daily and -days must be positive")
+	}
+	start := time.Now()
+	if *startDate != "" {
+		var err error
+		if start, err = time.ParseInLocation(planDateLayout, *startDate, time.Local); err != nil {
+			log.Fatalf("Error parsing -start: %v", err)
+		}
+	}
+	if _, err := time.Parse("15:04", *dayStart); err != nil {
+		log.Fatalf("Error: -day-start must be HH:MM, not %q", *dayStart)
+	}
+	if *icsGroup != "video" && *icsGroup != "category" {
+		log.Fatalf("Error: -ics-group must be video or category, not %q", *icsGroup)
+	}
+	if *output == "" {
+		switch *format {
+		case "json":
+			*output = "watch_plan.json"
+		case "ics":
+			*output = "watch_plan.ics"
+		default:
+			*output = "watch_plan.md"
+		}
+	}
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+	weights, err := parseCategoryWeights(*weightsFlag, rules)
+	if err != nil {
+		log.Fatalf("Error parsing -weights: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		log.Fatalf("Error reading overrides from library: %v", err)
+	}
+	markUnavailable(videos, "")
+
+	plan := buildWatchPlan(categorizeVideos(videos, rules, overrides), weights, *daily, start, *dayStart, *days, *prefer)
+	for _, day := range plan.Days {
+		fmt.Printf("%s: %d videos, %s\n", day.Date, len(day.Videos), formatWatchTime(day.Seconds))
+	}
+	if err := saveWatchPlan(*output, *format, plan, *icsGroup); err != nil {
+		log.Fatalf("Error saving watch plan: %v", err)
+	}
+	fmt.Printf("Watch plan saved to %s\n", *output)
+
+	if *syncToday {
+		added, err := syncTodayPlaylist(newYouTubeService(), lib, plan.Days[0])
+		if err != nil {
+			log.Fatalf("Error syncing %s playlist: %v", todayPlaylist, err)
+		}
+		fmt.Printf("Added %d videos to the %s playlist\n", added, todayPlaylist)
+	}
+}
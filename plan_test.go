@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// planVideo returns a video of the given length first seen on a day
+func planVideo(id string, minutes int, firstSeen string) Video {
+	video := testVideo(id, id)
+	video.DurationSeconds = minutes * 60
+	video.FirstSeen = firstSeen
+	return video
+}
+
+func TestBuildWatchPlan(t *testing.T) {
+	categorized := []CategorizedVideos{
+		{Category: "Kubernetes", Videos: []Video{
+			planVideo("k8s-new", 20, "2024-05-02"),
+			planVideo("k8s-old", 20, "2024-05-01"),
+			planVideo("k8s-long", 120, "2024-04-01"),
+		}},
+		{Category: "Databases", Videos: []Video{
+			planVideo("sql", 20, "2024-05-01"),
+			planVideo("sql-unknown", 0, "2024-05-01"),
+		}},
+		{Category: "Other", Videos: []Video{
+			planVideo("other", 10, "2024-05-01"),
+		}},
+	}
+	start := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	weights := map[string]float64{"Other": 0}
+
+	plan := buildWatchPlan(categorized, weights, 45*time.Minute, start, "19:00", 2, "oldest")
+
+	if len(plan.Days) != 2 || plan.Days[0].Date != "2024-05-06" || plan.Days[1].Date != "2024-05-07" {
+		t.Fatalf("days = %+v", plan.Days)
+	}
+	// The categories take turns, oldest video first, until the next one doesn't fit
+	var first []string
+	for _, video := range plan.Days[0].Videos {
+		first = append(first, video.ID)
+	}
+	if len(first) != 2 || first[0] != "k8s-old" || first[1] != "sql" || plan.Days[0].Seconds != 40*60 {
+		t.Errorf("first day = %v (%ds), want k8s-old and sql", first, plan.Days[0].Seconds)
+	}
+	if len(plan.Days[1].Videos) != 1 || plan.Days[1].Videos[0].ID != "k8s-new" {
+		t.Errorf("second day = %+v, want k8s-new", plan.Days[1].Videos)
+	}
+	if plan.TooLong != 1 || plan.NoDuration != 1 || plan.Unplanned != 0 {
+		t.Errorf("too long %d, no duration %d, unplanned %d; want 1, 1, 0", plan.TooLong, plan.NoDuration, plan.Unplanned)
+	}
+}
+
+func TestBuildWatchPlanFollowsWeights(t *testing.T) {
+	var kubernetes, databases []Video
+	for _, id := range []string{"k1", "k2", "k3", "k4"} {
+		kubernetes = append(kubernetes, planVideo(id, 10, "2024-05-01"))
+	}
+	for _, id := range []string{"d1", "d2", "d3", "d4"} {
+		databases = append(databases, planVideo(id, 10, "2024-05-01"))
+	}
+	categorized := []CategorizedVideos{{Category: "Kubernetes", Videos: kubernetes}, {Category: "Databases", Videos: databases}}
+	weights := map[string]float64{"Kubernetes": 2}
+
+	plan := buildWatchPlan(categorized, weights, time.Hour, time.Now(), "19:00", 1, "oldest")
+
+	counts := map[string]int{}
+	for _, video := range plan.Days[0].Videos {
+		counts[video.Category]++
+	}
+	if counts["Kubernetes"] != 4 || counts["Databases"] != 2 || plan.Unplanned != 2 {
+		t.Errorf("planned %v with %d left over, want 4 Kubernetes, 2 Databases and 2 left", counts, plan.Unplanned)
+	}
+}
+
+func TestTodayIsReserved(t *testing.T) {
+	rules := &Rules{Categories: []CategoryRule{{Name: todayPlaylist}}}
+	if err := rules.validate(); err == nil {
+		t.Errorf("a category called %q was accepted", todayPlaylist)
+	}
+	rules = &Rules{Categories: []CategoryRule{{Name: "Kubernetes"}}, SmartPlaylists: []SmartPlaylist{{Name: todayPlaylist, MaxMinutes: 10}}}
+	if err := rules.validate(); err == nil {
+		t.Errorf("a smart playlist called %q was accepted", todayPlaylist)
+	}
+}
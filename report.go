@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reportFormat writes the categorized videos in one output format
+type reportFormat struct {
+	Extension string
+	Write     func(w io.Writer, report categorizedReport) error
+}
+
+// reportFormats are the formats the categorized videos can be saved in,
+// selected with -format
+var reportFormats = map[string]reportFormat{
+	"json":     {Extension: ".json", Write: writeJSONReport},
+	"markdown": {Extension: ".md", Write: writeMarkdownReport},
+	"html":     {Extension: ".html", Write: writeHTMLReport},
+	"csv":      {Extension: ".csv", Write: writeCSVReport},
+	"tsv":      {Extension: ".tsv", Write: writeTSVReport},
+}
+
+// reportFormatNames returns the known format names, sorted
+func reportFormatNames() []string {
+	var names []string
+	for name := range reportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseReportFormats splits a comma-separated -format value and checks every
+// format is known
+func parseReportFormats(value string) ([]string, error) {
+	var formats []string
+	for _, format := range strings.Split(value, ",") {
+		format = strings.TrimSpace(format)
+		if format == "" {
+			continue
+		}
+		if _, ok := reportFormats[format]; !ok {
+			return nil, fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(reportFormatNames(), ", "))
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// saveReport writes the report to base plus the format's extension and
+// returns the filename
+func saveReport(base, format string, report categorizedReport) (string, error) {
+	output := reportFormats[format]
+	filename := base + output.Extension
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := output.Write(w, report); err != nil {
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return filename, file.Close()
+}
+
+// writeJSONReport writes the versioned categorized_videos.json, see schema.go
+func writeJSONReport(w io.Writer, report categorizedReport) error {
+	bytes, err := json.MarshalIndent(newCategorizedFile(report.Run, report.Parents, report.Categories), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+// categoryWatchTime adds up the known durations of a category's videos
+func categoryWatchTime(videos []Video) int {
+	seconds := 0
+	for _, video := range videos {
+		seconds += video.DurationSeconds
+	}
+	return seconds
+}
+
+// markdownEscaper escapes characters in titles that would break a Markdown
+// link or a table, or be read as inline HTML
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "<", `\<`, ">", `\>`, "|", `\|`)
+
+// writeMarkdownReport writes a section per category with linked titles,
+// channel and duration, for pasting into a wiki or gist
+func writeMarkdownReport(w io.Writer, report categorizedReport) error {
+	fmt.Fprintf(w, "# Watch Later backlog\n\nGenerated %s.\n", report.Run.GeneratedAt)
+	for _, category := range reportTree(report) {
+		fmt.Fprintf(w, "\n## %s\n\n%d videos, %s", category.Path, len(category.Videos), formatWatchTime(categoryWatchTime(category.Videos)))
+		if category.HasChildren {
+			fmt.Fprintf(w, " (%d videos, %s with subcategories)", len(category.Rollup), formatWatchTime(categoryWatchTime(category.Rollup)))
+		}
+		fmt.Fprint(w, "\n\n")
+		for _, video := range category.Videos {
+			title := markdownEscaper.Replace(video.Title)
+			if video.Link != "" {
+				title = "[" + title + "](" + video.Link + ")"
+			}
+			_, err := fmt.Fprintf(w, "- %s (%s, %s)\n", title, markdownEscaper.Replace(orDefault(video.Channel, "unknown channel")), formatDuration(video.DurationSeconds))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeHTMLReport writes a standalone page with collapsible categories and
+// a search box that filters videos in the browser
+func writeHTMLReport(w io.Writer, report categorizedReport) error {
+	return reportTemplate.Execute(w, struct {
+		Generated  string
+		Categories []reportCategory
+	}{report.Run.GeneratedAt, reportTree(report)})
+}
+
+// reportCategory is a category as the Markdown and HTML reports show it
+type reportCategory struct {
+	Path   string
+	Videos []Video
+
+	// Rollup is the category's videos and its subcategories', set when
+	// HasChildren
+	HasChildren bool
+	Rollup      []Video
+}
+
+// reportTree lists the categories with videos, each parent followed by its
+// subcategories
+func reportTree(report categorizedReport) []reportCategory {
+	rolled := rollUp(report.Parents, report.Categories)
+	var tree []reportCategory
+	var visit func(parent string)
+	visit = func(parent string) {
+		for _, catVideos := range report.Categories {
+			if report.Parents[catVideos.Category] != parent {
+				continue
+			}
+			if len(rolled[catVideos.Category]) > 0 {
+				tree = append(tree, reportCategory{
+					Path:        categoryPath(report.Parents, catVideos.Category),
+					Videos:      catVideos.Videos,
+					HasChildren: hasChildren(report.Parents, catVideos.Category),
+					Rollup:      rolled[catVideos.Category],
+				})
+			}
+			visit(catVideos.Category)
+		}
+	}
+	visit("")
+	return tree
+}
+
+// reportTemplate is the standalone HTML report, with no external assets
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration":  formatDuration,
+	"watchTime": func(videos []Video) string { return formatWatchTime(categoryWatchTime(videos)) },
+	"lower":     strings.ToLower,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Watch Later backlog</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+input[type=search] { width: 100%; padding: .5rem; font-size: 1rem; margin-bottom: 1rem; }
+details { border-bottom: 1px solid #ddd; padding: .5rem 0; }
+summary { cursor: pointer; font-weight: 600; }
+summary span { font-weight: normal; color: #666; }
+ul { list-style: none; padding-left: 1rem; }
+li { padding: .2rem 0; }
+.meta { color: #666; font-size: .9em; }
+</style>
+</head>
+<body>
+<h1>Watch Later backlog</h1>
+<p class="meta">Generated {{.Generated}}</p>
+<input type="search" id="search" placeholder="Search titles and channels" autofocus>
+{{range .Categories}}
+<details open>
+<summary>{{.Path}} <span>({{len .Videos}} videos, {{watchTime .Videos}}{{if .HasChildren}}; {{len .Rollup}} videos, {{watchTime .Rollup}} with subcategories{{end}})</span></summary>
+<ul>
+{{range .Videos}}<li data-search="{{lower .Title}} {{lower .Channel}}">{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}} <span class="meta">{{.Channel}} · {{duration .DurationSeconds}}</span></li>
+{{end}}</ul>
+</details>
+{{end}}
+<script>
+document.getElementById("search").addEventListener("input", function () {
+  var query = this.value.toLowerCase().trim();
+  document.querySelectorAll("details").forEach(function (details) {
+    var shown = 0;
+    details.querySelectorAll("li").forEach(function (item) {
+      var match = item.dataset.search.indexOf(query) !== -1;
+      item.hidden = !match;
+      if (match) shown++;
+    });
+    details.hidden = shown === 0;
+    if (query) details.open = true;
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// escapingReport has a video whose title and channel need escaping
+func escapingReport() categorizedReport {
+	video := testVideo("aaaaaaaaaaa", "<script>alert(1)</script> | Helm [part 1] *live*")
+	video.Channel = "Nana_<b>"
+	video.DurationSeconds = 125
+	return categorizedReport{
+		Run:        reportRun{GeneratedAt: "2024-06-01T00:00:00Z"},
+		Categories: []CategorizedVideos{{Category: "Kubernetes", Videos: []Video{video}}},
+	}
+}
+
+func TestWriteMarkdownReportEscapes(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeMarkdownReport(&b, escapingReport()); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	want := "- [\\<script\\>alert(1)\\</script\\> \\| Helm \\[part 1\\] \\*live\\*](https://www.youtube.com/watch?v=aaaaaaaaaaa) (Nana\\_\\<b\\>, 2:05)\n"
+	if !strings.Contains(got, want) {
+		t.Errorf("report is missing the escaped line %q:\n%s", want, got)
+	}
+	if !strings.Contains(got, "## Kubernetes\n\n1 videos, 2m") {
+		t.Errorf("report is missing the category heading:\n%s", got)
+	}
+}
+
+func TestWriteHTMLReportEscapes(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeHTMLReport(&b, escapingReport()); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, raw := range []string{"<script>alert", "<b>"} {
+		if strings.Contains(got, raw) {
+			t.Errorf("report contains unescaped %q", raw)
+		}
+	}
+	for _, escaped := range []string{
+		">&lt;script&gt;alert(1)&lt;/script&gt; | Helm [part 1] *live*</a>",
+		"Nana_&lt;b&gt; · 2:05",
+	} {
+		if !strings.Contains(got, escaped) {
+			t.Errorf("report is missing %q", escaped)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ruleFields are the parts of a video keywords can be matched against
+var ruleFields = []string{"title", "tags", "description", "channel"}
+
+// Rules is the categorization configuration loaded from rules.json
+type Rules struct {
+	// Fields weights each field a keyword can match in. A field counts once
+	// however many keywords match in it, and a video goes to the category with
+	// the highest total. Ties go to the category listed first.
+	Fields map[string]float64 `json:"fields"`
+
+	// Ignore leaves videos out before they are categorized, see ignore.go
+	Ignore *IgnoreRules `json:"ignore,omitempty"`
+
+	// Matching loosens how keywords match for every category, see
+	// matching.go
+	Matching *Matching `json:"matching,omitempty"`
+
+	Categories []CategoryRule `json:"categories"`
+
+	// Languages routes videos that aren't in the primary language, see
+	// language.go
+	Languages *LanguageRules `json:"languages,omitempty"`
+
+	// PlaylistTitle is the title of each category's playlist, with {name}
+	// replaced by the category name and {path} by its name after its
+	// parents', e.g. "Cloud / AWS". It defaults to "{name} Playlist".
+	PlaylistTitle string `json:"playlistTitle,omitempty"`
+
+	// SmartPlaylists are extra playlists selected by duration, see smart.go
+	SmartPlaylists []SmartPlaylist `json:"smartPlaylists,omitempty"`
+
+	// Classifier adds a naive Bayes classifier trained with the train
+	// command, see classifier.go
+	Classifier *ClassifierConfig `json:"classifier,omitempty"`
+
+	// Embeddings categorizes videos by meaning rather than keywords, see
+	// embeddings.go
+	Embeddings *EmbeddingsConfig `json:"embeddings,omitempty"`
+
+	// model is the classifier model, loaded by loadRules
+	model *bayesModel
+
+	// semantic compares videos with categories when Embeddings is set
+	semantic *semanticCategorizer
+}
+
+// CategoryRule is a single category and the keywords that select it
+type CategoryRule struct {
+	Name string `json:"name"`
+
+	// Parent nests the category under another, see hierarchy.go
+	Parent string `json:"parent,omitempty"`
+
+	Keywords []string `json:"keywords"`
+
+	// LanguageKeywords are extra keywords for videos in a language, by
+	// language code, e.g. {"de": ["datenbank"]}
+	LanguageKeywords map[string][]string `json:"languageKeywords,omitempty"`
+
+	// Exclude and ExcludePatterns (regular expressions) are exceptions to
+	// the keywords: text they match is blanked out before the keywords are
+	// matched, so "let's go" keeps "go" from matching
+	Exclude         []string `json:"exclude,omitempty"`
+	ExcludePatterns []string `json:"excludePatterns,omitempty"`
+
+	// Matching replaces the top-level matching for this category, and
+	// KeywordMatching replaces it for single keywords, e.g. {"go": {}} keeps
+	// "go" exact while the rest are stemmed
+	Matching        *Matching            `json:"matching,omitempty"`
+	KeywordMatching map[string]*Matching `json:"keywordMatching,omitempty"`
+
+	// Fields replaces the top-level field weights for this category
+	Fields map[string]float64 `json:"fields,omitempty"`
+
+	// Description and Examples (video titles) say what the category is
+	// about when embeddings are on; its name and keywords are used otherwise
+	Description string   `json:"description,omitempty"`
+	Examples    []string `json:"examples,omitempty"`
+
+	// excludePatterns are the compiled ExcludePatterns, set by validate
+	excludePatterns []*regexp.Regexp
+
+	// matching is Matching, or the top-level matching, set by validate
+	matching Matching
+}
+
+// loadRules reads and validates a rules file
+func loadRules(filename string) (*Rules, error) {
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(bytes, &rules); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	// A missing model just means train hasn't been run yet
+	if rules.Classifier != nil {
+		rules.model, err = loadBayesModel(rules.Classifier.Model)
+		if os.IsNotExist(err) {
+			fmt.Printf("Classifier model %s not found, run `go run . train` to create it\n", rules.Classifier.Model)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if rules.Embeddings != nil {
+		rules.semantic = newSemanticCategorizer(rules.Embeddings)
+	}
+	return &rules, nil
+}
+
+// validate checks category names are unique and only known fields are weighted
+func (r *Rules) validate() error {
+	if len(r.Fields) == 0 {
+		r.Fields = map[string]float64{"title": 1}
+	}
+	if err := validateFields(r.Fields); err != nil {
+		return err
+	}
+	if err := r.Matching.validate(); err != nil {
+		return fmt.Errorf("matching: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, category := range r.Categories {
+		switch {
+		case category.Name == "":
+			return fmt.Errorf("category with no name")
+		case category.Name == otherCategory || category.Name == excludeCategory || category.Name == todayPlaylist:
+			return fmt.Errorf("%q is reserved and can't be used as a category name", category.Name)
+		case seen[category.Name]:
+			return fmt.Errorf("category %q is defined twice", category.Name)
+		}
+		seen[category.Name] = true
+
+		if err := validateFields(category.Fields); err != nil {
+			return fmt.Errorf("category %q: %v", category.Name, err)
+		}
+		for language := range category.LanguageKeywords {
+			if language != normalizeLanguage(language) || language == "" {
+				return fmt.Errorf("category %q: language %q should be a lower-case code like \"de\"", category.Name, language)
+			}
+		}
+
+		r.Categories[i].excludePatterns = nil
+		for _, pattern := range category.ExcludePatterns {
+			compiled, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return fmt.Errorf("category %q: exclude pattern %q: %v", category.Name, pattern, err)
+			}
+			r.Categories[i].excludePatterns = append(r.Categories[i].excludePatterns, compiled)
+		}
+
+		if err := category.Matching.validate(); err != nil {
+			return fmt.Errorf("category %q: matching: %v", category.Name, err)
+		}
+		for keyword, matching := range category.KeywordMatching {
+			if err := matching.validate(); err != nil {
+				return fmt.Errorf("category %q: matching for %q: %v", category.Name, keyword, err)
+			}
+		}
+		r.Categories[i].matching = orMatching(category.Matching, r.Matching)
+	}
+
+	if err := r.validateHierarchy(); err != nil {
+		return err
+	}
+
+	if r.Ignore != nil {
+		if err := r.Ignore.validate(); err != nil {
+			return err
+		}
+	}
+	if r.Languages != nil {
+		if err := r.Languages.validate(r.categoryNames()); err != nil {
+			return err
+		}
+	}
+
+	smartNames := make(map[string]bool)
+	for _, smart := range r.SmartPlaylists {
+		if err := smart.validate(r.categoryNames()); err != nil {
+			return err
+		}
+		if smartNames[smart.Name] {
+			return fmt.Errorf("smart playlist %q is defined twice", smart.Name)
+		}
+		smartNames[smart.Name] = true
+	}
+
+	if r.Embeddings != nil {
+		if err := r.Embeddings.validate(); err != nil {
+			return err
+		}
+	}
+	if r.Classifier != nil {
+		return r.Classifier.validate()
+	}
+	return nil
+}
+
+// validateFields rejects weights for fields that don't exist
+func validateFields(fields map[string]float64) error {
+	for field := range fields {
+		if !containsString(ruleFields, field) {
+			return fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(ruleFields, ", "))
+		}
+	}
+	return nil
+}
+
+// categoryNames returns the category names in rule order
+func (r *Rules) categoryNames() []string {
+	names := make([]string, len(r.Categories))
+	for i, category := range r.Categories {
+		names[i] = category.Name
+	}
+	return names
+}
+
+// fieldText returns the text of a video field that keywords are matched against
+func fieldText(video Video, field string) string {
+	switch field {
+	case "title":
+		return video.Title
+	case "tags":
+		return strings.Join(video.Tags, "\n")
+	case "description":
+		return video.Description
+	case "channel":
+		return video.Channel
+	}
+	return ""
+}
+
+// excludeMask replaces excluded text; keywords can't match across it
+const excludeMask = "\x00"
+
+// mask blanks out the parts of lower-case text the category's exclusions match
+func (c CategoryRule) mask(text string) string {
+	for _, exclude := range c.Exclude {
+		if exclude != "" {
+			text = strings.ReplaceAll(text, strings.ToLower(exclude), excludeMask)
+		}
+	}
+	for _, pattern := range c.excludePatterns {
+		text = pattern.ReplaceAllLiteralString(text, excludeMask)
+	}
+	return text
+}
+
+// keywordMatching returns how a keyword is matched: its own matching if it
+// has one, otherwise the category's
+func (c CategoryRule) keywordMatching(keyword string) Matching {
+	for name, matching := range c.KeywordMatching {
+		if strings.EqualFold(name, keyword) {
+			return orMatching(matching)
+		}
+	}
+	return c.matching
+}
+
+// score matches a category's keywords against each weighted field of the
+// video, with the category's exclusions masked out and the keywords for the
+// video's language added, returning the total weight of the fields that
+// matched and the keywords that matched in them
+func (c CategoryRule) score(video Video, defaults map[string]float64) (float64, []string) {
+	fields := c.Fields
+	if len(fields) == 0 {
+		fields = defaults
+	}
+
+	keywords := c.Keywords
+	if extra := c.LanguageKeywords[video.Language]; len(extra) > 0 {
+		keywords = append(append([]string{}, keywords...), extra...)
+	}
+
+	total := 0.0
+	var matched []string
+	for _, field := range ruleFields {
+		weight := fields[field]
+		if weight == 0 {
+			continue
+		}
+		text := c.mask(strings.ToLower(fieldText(video, field)))
+		if text == "" {
+			continue
+		}
+
+		// The text is normalized once for each way keywords are matched
+		normalized := make(map[Matching]string)
+		fieldMatched := false
+		for _, keyword := range keywords {
+			matching := c.keywordMatching(keyword)
+			if _, ok := normalized[matching]; !ok {
+				normalized[matching] = matching.normalize(text)
+			}
+			if matching.contains(normalized[matching], matching.normalize(strings.ToLower(keyword)), keywordLetters(keyword)) {
+				fieldMatched = true
+				if !containsFold(matched, keyword) {
+					matched = append(matched, keyword)
+				}
+			}
+		}
+		if fieldMatched {
+			total += weight
+		}
+	}
+	return total, matched
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rulesPatch is a set of suggested changes to rules.json, written by the
+// discover and suggest commands so it can be reviewed before it is applied
+type rulesPatch struct {
+	AddCategories []CategoryRule `json:"addCategories,omitempty"`
+
+	// AddKeywords and RemoveKeywords are keywords by category name
+	AddKeywords    map[string][]string `json:"addKeywords,omitempty"`
+	RemoveKeywords map[string][]string `json:"removeKeywords,omitempty"`
+}
+
+// apply adds the patch to the rules and checks the result is still valid.
+// Keywords a category already has are not added twice.
+func (p rulesPatch) apply(rules *Rules) error {
+	rules.Categories = append(rules.Categories, p.AddCategories...)
+	for _, changes := range []map[string][]string{p.AddKeywords, p.RemoveKeywords} {
+		for name := range changes {
+			if !containsString(rules.categoryNames(), name) {
+				return fmt.Errorf("patch changes keywords of unknown category %q", name)
+			}
+		}
+	}
+
+	for i, category := range rules.Categories {
+		var keywords []string
+		for _, keyword := range category.Keywords {
+			if !containsFold(p.RemoveKeywords[category.Name], keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+		for _, keyword := range p.AddKeywords[category.Name] {
+			if !containsFold(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+		rules.Categories[i].Keywords = keywords
+	}
+	return rules.validate()
+}
+
+// printChanges lists what the patch changes, category by category
+func (p rulesPatch) printChanges() {
+	for _, category := range p.AddCategories {
+		fmt.Printf("Added category %s\n", category.Name)
+	}
+	for _, name := range sortedKeys(p.AddKeywords) {
+		fmt.Printf("Added to %s: %s\n", name, strings.Join(p.AddKeywords[name], ", "))
+	}
+	for _, name := range sortedKeys(p.RemoveKeywords) {
+		fmt.Printf("Removed from %s: %s\n", name, strings.Join(p.RemoveKeywords[name], ", "))
+	}
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadRulesPatch reads a patch written by saveRulesPatch
+func loadRulesPatch(filename string) (rulesPatch, error) {
+	var patch rulesPatch
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return patch, err
+	}
+	if err := json.Unmarshal(bytes, &patch); err != nil {
+		return patch, fmt.Errorf("%s: %v", filename, err)
+	}
+	return patch, nil
+}
+
+// saveRulesPatch writes a patch as indented JSON
+func saveRulesPatch(filename string, patch rulesPatch) error {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(patch); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b.Bytes(), 0644)
+}
+
+// compactJSON marshals v on one line, with a space after each colon and
+// comma and without escaping &, < and >
+func compactJSON(v any) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		panic(err) // only called with types that always marshal
+	}
+
+	var spaced strings.Builder
+	inString, escaped := false, false
+	for _, r := range strings.TrimSpace(b.String()) {
+		spaced.WriteRune(r)
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case !inString && (r == ':' || r == ','):
+			spaced.WriteRune(' ')
+		}
+	}
+	return spaced.String()
+}
+
+// compactFields writes field weights on one line in ruleFields order
+func compactFields(fields map[string]float64) string {
+	var parts []string
+	for _, field := range ruleFields {
+		if weight, ok := fields[field]; ok {
+			number := strconv.FormatFloat(weight, 'f', -1, 64)
+			if !strings.Contains(number, ".") {
+				number += ".0"
+			}
+			parts = append(parts, fmt.Sprintf("%q: %s", field, number))
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// formatRules lays rules out the way rules.json is written by hand: one line
+// per keyword list, smart playlist and setting, so diffs stay readable
+func formatRules(rules *Rules) []byte {
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	fmt.Fprintf(&b, "  \"fields\": %s,\n", compactFields(rules.Fields))
+	if rules.Matching != nil {
+		fmt.Fprintf(&b, "  \"matching\": %s,\n", compactJSON(rules.Matching))
+	}
+	if rules.Languages != nil {
+		fmt.Fprintf(&b, "  \"languages\": %s,\n", compactJSON(rules.Languages))
+	}
+	if rules.PlaylistTitle != "" {
+		fmt.Fprintf(&b, "  \"playlistTitle\": %s,\n", compactJSON(rules.PlaylistTitle))
+	}
+	if rules.Ignore != nil {
+		fmt.Fprintf(&b, "  \"ignore\": %s,\n", compactJSON(rules.Ignore))
+	}
+	b.WriteString("  \"categories\": [\n")
+	for i, category := range rules.Categories {
+		b.WriteString("    {\n")
+		fmt.Fprintf(&b, "      \"name\": %s,\n", compactJSON(category.Name))
+		if category.Parent != "" {
+			fmt.Fprintf(&b, "      \"parent\": %s,\n", compactJSON(category.Parent))
+		}
+		fmt.Fprintf(&b, "      \"keywords\": %s", compactJSON(orEmpty(category.Keywords)))
+		if len(category.LanguageKeywords) > 0 {
+			fmt.Fprintf(&b, ",\n      \"languageKeywords\": %s", compactJSON(category.LanguageKeywords))
+		}
+		if len(category.Exclude) > 0 {
+			fmt.Fprintf(&b, ",\n      \"exclude\": %s", compactJSON(category.Exclude))
+		}
+		if len(category.ExcludePatterns) > 0 {
+			fmt.Fprintf(&b, ",\n      \"excludePatterns\": %s", compactJSON(category.ExcludePatterns))
+		}
+		if category.Matching != nil {
+			fmt.Fprintf(&b, ",\n      \"matching\": %s", compactJSON(category.Matching))
+		}
+		if len(category.KeywordMatching) > 0 {
+			fmt.Fprintf(&b, ",\n      \"keywordMatching\": %s", compactJSON(category.KeywordMatching))
+		}
+		if len(category.Fields) > 0 {
+			fmt.Fprintf(&b, ",\n      \"fields\": %s", compactFields(category.Fields))
+		}
+		if category.Description != "" {
+			fmt.Fprintf(&b, ",\n      \"description\": %s", compactJSON(category.Description))
+		}
+		if len(category.Examples) > 0 {
+			fmt.Fprintf(&b, ",\n      \"examples\": %s", compactJSON(category.Examples))
+		}
+		b.WriteString("\n    }")
+		if i < len(rules.Categories)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("  ]")
+
+	if len(rules.SmartPlaylists) > 0 {
+		b.WriteString(",\n  \"smartPlaylists\": [\n")
+		for i, smart := range rules.SmartPlaylists {
+			b.WriteString("    " + compactJSON(smart))
+			if i < len(rules.SmartPlaylists)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("  ]")
+	}
+	if rules.Classifier != nil {
+		fmt.Fprintf(&b, ",\n  \"classifier\": %s", compactJSON(rules.Classifier))
+	}
+	if rules.Embeddings != nil {
+		fmt.Fprintf(&b, ",\n  \"embeddings\": %s", compactJSON(rules.Embeddings))
+	}
+	b.WriteString("\n}\n")
+	return b.Bytes()
+}
+
+// orEmpty returns list, or an empty list instead of nil so it marshals as []
+func orEmpty(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// saveRules writes rules back to a rules file
+func saveRules(filename string, rules *Rules) error {
+	return os.WriteFile(filename, formatRules(rules), 0644)
+}
+
+// runPatch implements the `patch` command, which applies a reviewed rules
+// patch from discover or suggest to the rules file
+func runPatch(args []string) {
+	flags := flag.NewFlagSet("patch", flag.ExitOnError)
+	rulesPath := flags.String("rules", "rules.json", "rules file to change")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: go run . patch [-rules rules.json] <suggested_rules.json>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+	patch, err := loadRulesPatch(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Error loading patch: %v", err)
+	}
+	if err := patch.apply(rules); err != nil {
+		log.Fatalf("Error applying patch: %v", err)
+	}
+	if err := saveRules(*rulesPath, rules); err != nil {
+		log.Fatalf("Error saving rules: %v", err)
+	}
+	patch.printChanges()
+	fmt.Printf("Rules saved to %s\n", *rulesPath)
+}
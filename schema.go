@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// categorizedSchemaVersion is the version of the categorized_videos.json
+// format written by writeJSONReport, described by
+// categorized_videos.schema.json. Bump it whenever a field is removed or
+// changes meaning; adding optional fields doesn't need a new version.
+//
+// Version 1 (before schemaVersion existed) was a bare array of
+// {category, videos} groups.
+const categorizedSchemaVersion = 2
+
+// reportRun describes the run that produced a report
+type reportRun struct {
+	GeneratedAt string   `json:"generatedAt"`
+	RulesFile   string   `json:"rulesFile,omitempty"`
+	RulesSHA256 string   `json:"rulesSha256,omitempty"`
+	Library     string   `json:"library,omitempty"`
+	Scrapes     []string `json:"scrapes"`
+}
+
+// categorizedReport is what the report writers in report.go are given
+type categorizedReport struct {
+	Run        reportRun
+	Categories []CategorizedVideos
+
+	// Parents maps each nested category to its parent, see hierarchy.go
+	Parents map[string]string
+}
+
+// categorySummary is a category's entry in categorized_videos.json
+type categorySummary struct {
+	Name            string `json:"name"`
+	Parent          string `json:"parent,omitempty"`
+	VideoCount      int    `json:"videoCount"`
+	DurationSeconds int    `json:"durationSeconds"`
+
+	// Rollup totals the category and all its subcategories, for categories
+	// that have some
+	Rollup *categoryTotals `json:"rollup,omitempty"`
+}
+
+// categoryTotals is the size of a category including its subcategories
+type categoryTotals struct {
+	VideoCount      int `json:"videoCount"`
+	DurationSeconds int `json:"durationSeconds"`
+}
+
+// categorizedVideo is a video's entry in categorized_videos.json
+type categorizedVideo struct {
+	Video
+	Category string `json:"category"`
+
+	// UncategorizedReason explains why a video ended up in Other
+	UncategorizedReason string `json:"uncategorizedReason,omitempty"`
+}
+
+// categorizedFile is version 2 of categorized_videos.json
+type categorizedFile struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Run           reportRun          `json:"run"`
+	Categories    []categorySummary  `json:"categories"`
+	Videos        []categorizedVideo `json:"videos"`
+}
+
+// uncategorizedReason explains why a video is in Other, or returns "" if it isn't
+func uncategorizedReason(video Video, category string) string {
+	switch {
+	case category != otherCategory:
+		return ""
+	case video.Override == otherCategory:
+		return "overridden to " + otherCategory
+	case video.Title == "" && video.Description == "" && len(video.Tags) == 0 && video.Channel == "":
+		return "no text to match keywords against"
+	case video.Prediction != "" && video.Prediction != otherCategory:
+		return fmt.Sprintf("no keywords matched and the classifier was only %.0f%% sure of %s", 100*video.PredictionConfidence, video.Prediction)
+	case video.SemanticCategory != "":
+		return fmt.Sprintf("no keywords matched and the closest category, %s, was only %.2f similar", video.SemanticCategory, video.Similarity)
+	}
+	return "no keywords matched"
+}
+
+// newCategorizedFile flattens categorized videos into the versioned format
+func newCategorizedFile(run reportRun, parents map[string]string, categorizedVideos []CategorizedVideos) categorizedFile {
+	file := categorizedFile{
+		SchemaVersion: categorizedSchemaVersion,
+		Run:           run,
+		Categories:    []categorySummary{},
+		Videos:        []categorizedVideo{},
+	}
+	if file.Run.Scrapes == nil {
+		file.Run.Scrapes = []string{}
+	}
+	rolled := rollUp(parents, categorizedVideos)
+	for _, catVideos := range categorizedVideos {
+		summary := categorySummary{
+			Name:            catVideos.Category,
+			Parent:          parents[catVideos.Category],
+			VideoCount:      len(catVideos.Videos),
+			DurationSeconds: categoryWatchTime(catVideos.Videos),
+		}
+		if hasChildren(parents, catVideos.Category) {
+			videos := rolled[catVideos.Category]
+			summary.Rollup = &categoryTotals{VideoCount: len(videos), DurationSeconds: categoryWatchTime(videos)}
+		}
+		file.Categories = append(file.Categories, summary)
+		for _, video := range catVideos.Videos {
+			file.Videos = append(file.Videos, categorizedVideo{
+				Video:               video,
+				Category:            catVideos.Category,
+				UncategorizedReason: uncategorizedReason(video, catVideos.Category),
+			})
+		}
+	}
+	return file
+}
+
+// readCategorizedVideos reads categorized_videos.json in any version: the
+// bare array of version 1 is upgraded to the current format, with no run
+// information. Files from a newer version are rejected rather than misread.
+func readCategorizedVideos(filename string) (categorizedFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return categorizedFile{}, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var groups []CategorizedVideos
+		if err := json.Unmarshal(trimmed, &groups); err != nil {
+			return categorizedFile{}, fmt.Errorf("%s: %v", filename, err)
+		}
+		// Version 1 had no id field, so take it from the link like a scrape
+		for _, group := range groups {
+			for i := range group.Videos {
+				if group.Videos[i].ID == "" {
+					group.Videos[i].ID = extractVideoID(group.Videos[i].Link)
+				}
+			}
+		}
+		file := newCategorizedFile(reportRun{}, nil, groups)
+		file.SchemaVersion = 1
+		return file, nil
+	}
+
+	var file categorizedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return categorizedFile{}, fmt.Errorf("%s: %v", filename, err)
+	}
+	if file.SchemaVersion < 2 || file.SchemaVersion > categorizedSchemaVersion {
+		return categorizedFile{}, fmt.Errorf("%s: unsupported schemaVersion %d, this version reads up to %d", filename, file.SchemaVersion, categorizedSchemaVersion)
+	}
+	return file, nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file, used to tell which rules produced a report
+func fileSHA256(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schemaFormats are the layouts of the formats categorized_videos.schema.json uses
+var schemaFormats = map[string]string{
+	"date":      "2006-01-02",
+	"date-time": time.RFC3339,
+}
+
+// checkSchema validates a decoded JSON value against the subset of JSON
+// Schema that categorized_videos.schema.json uses, returning every problem
+func checkSchema(root, schema map[string]interface{}, value interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			def = def[part].(map[string]interface{})
+		}
+		return checkSchema(root, def, value, path)
+	}
+
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+	if want, ok := schema["const"]; ok && value != want {
+		fail("got %v, want %v", value, want)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("not an object")
+			return problems
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				fail("missing %q", name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := properties[name].(map[string]interface{}); ok {
+				problems = append(problems, checkSchema(root, property, object[name], path+"."+name)...)
+			} else if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				problems = append(problems, checkSchema(root, additional, object[name], path+"."+name)...)
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			fail("not an array")
+			return problems
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range array {
+				problems = append(problems, checkSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("not a string")
+			return problems
+		}
+		if format, ok := schema["format"].(string); ok {
+			if _, err := time.Parse(schemaFormats[format], s); err != nil {
+				fail("%q is not a %s", s, format)
+			}
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			fail("%q doesn't match %s", s, pattern)
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok || (schema["type"] == "integer" && n != math.Trunc(n)) {
+			fail("not an %s", schema["type"])
+			return problems
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+			fail("%v is below %v", n, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && n > maximum {
+			fail("%v is above %v", n, maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("not a boolean")
+		}
+	}
+	return problems
+}
+
+func TestWriteJSONReportMatchesSchema(t *testing.T) {
+	data, err := os.ReadFile("categorized_videos.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	// A video as a merged scrape and enrich leave it
+	dir := t.TempDir()
+	scrapeFile := filepath.Join(dir, "scrape-2024-05-01.json")
+	if err := os.WriteFile(scrapeFile, []byte(`[{"title": "Helm charts", "link": "https://www.youtube.com/watch?v=aaaaaaaaaaa"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	videos, _, err := mergeScrapes([]string{scrapeFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := videos[0]
+	video.PublishedAt = "2023-01-02T03:04:05Z"
+	video.DurationSeconds = 600
+	video.Score = 1
+	video.Scores = map[string]float64{"Kubernetes": 1}
+	video.MatchedKeywords = []string{"helm"}
+	other := testVideo("bbbbbbbbbbb", "Sourdough bread")
+
+	report := categorizedReport{
+		Run:        reportRun{GeneratedAt: now(), RulesSHA256: strings.Repeat("ab", 32), Scrapes: []string{scrapeFile}},
+		Categories: []CategorizedVideos{{Category: "Cloud"}, {Category: "Kubernetes", Videos: []Video{video}}, {Category: otherCategory, Videos: []Video{other}}},
+		Parents:    map[string]string{"Kubernetes": "Cloud"},
+	}
+	var b bytes.Buffer
+	if err := writeJSONReport(&b, report); err != nil {
+		t.Fatal(err)
+	}
+	var file interface{}
+	if err := json.Unmarshal(b.Bytes(), &file); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), `"firstSeen": "2024-05-01"`) {
+		t.Fatalf("report has no firstSeen date to check:\n%s", b.String())
+	}
+	for _, problem := range checkSchema(schema, schema, file, "$") {
+		t.Error(problem)
+	}
+}
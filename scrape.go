@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scrapeDateLayout is the format used for scrape dates and first/last seen dates
+const scrapeDateLayout = "2006-01-02"
+
+// scrapeDatePattern finds a date such as 2024-05-01 in a scrape file name
+var scrapeDatePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// scrape is a single scrape file and the day it was taken
+type scrape struct {
+	file string
+	date time.Time
+}
+
+// collectScrapeFiles expands files and directories into a list of scrape files.
+// In a directory, only .json files that hold a scrape are used, so rules.json,
+// token.json and reports saved alongside the scrapes are skipped.
+func collectScrapeFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if isScrapeFile(match) {
+				files = append(files, match)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no scrape files found in %s", strings.Join(paths, ", "))
+	}
+	return files, nil
+}
+
+// isScrapeFile reports whether a file is a scrape: a list of videos that
+// each have a title or a link
+func isScrapeFile(filename string) bool {
+	videos, err := readScrapeJSON(filename)
+	if err != nil {
+		return false
+	}
+	for _, video := range videos {
+		if video.Title == "" && video.Link == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// scrapeDate works out when a scrape was taken. A date in the file name
+// (e.g. scrape-2024-05-01.json) wins, otherwise the modification time is used.
+func scrapeDate(filename string) (time.Time, error) {
+	if match := scrapeDatePattern.FindString(filepath.Base(filename)); match != "" {
+		if date, err := time.Parse(scrapeDateLayout, match); err == nil {
+			return date, nil
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime().Truncate(24 * time.Hour), nil
+}
+
+// videoKey identifies a video across scrapes, falling back to the title when
+// the link does not contain a video ID
+func videoKey(video Video) string {
+	if video.ID != "" {
+		return video.ID
+	}
+	return "title:" + video.Title
+}
+
+// mergeScrapes reads every scrape file oldest first, dedupes videos by ID and
+// records the first and last scrape each video was seen in. It returns the
+// videos in the latest scrape and the videos that have since disappeared from
+// Watch Later (watched or removed).
+func mergeScrapes(filenames []string) ([]Video, []Video, error) {
+	scrapes := make([]scrape, 0, len(filenames))
+	for _, filename := range filenames {
+		date, err := scrapeDate(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		scrapes = append(scrapes, scrape{file: filename, date: date})
+	}
+	sort.SliceStable(scrapes, func(i, j int) bool {
+		return scrapes[i].date.Before(scrapes[j].date)
+	})
+
+	merged := make(map[string]*Video)
+	var order, latestOrder []string
+	var latest map[string]bool
+
+	for _, s := range scrapes {
+		videos, err := readScrapeJSON(s.file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", s.file, err)
+		}
+
+		seenDate := s.date.Format(scrapeDateLayout)
+		latest = make(map[string]bool)
+		latestOrder = nil
+		duplicates, noID := 0, 0
+
+		for _, video := range videos {
+			video.ID = extractVideoID(video.Link)
+			key := videoKey(video)
+			if latest[key] {
+				duplicates++
+				continue
+			}
+			if video.ID == "" {
+				noID++
+			}
+			latest[key] = true
+			latestOrder = append(latestOrder, key)
+
+			existing, ok := merged[key]
+			if !ok {
+				video.FirstSeen = seenDate
+				video.LastSeen = seenDate
+				stored := video
+				merged[key] = &stored
+				order = append(order, key)
+				continue
+			}
+
+			// Keep the newest title and label but the original first seen date
+			video.FirstSeen = existing.FirstSeen
+			video.LastSeen = seenDate
+			*existing = video
+		}
+
+		fmt.Printf("Read %d videos from %s (%s)", len(videos), s.file, seenDate)
+		if duplicates > 0 {
+			fmt.Printf(", skipped %d duplicates", duplicates)
+		}
+		if noID > 0 {
+			fmt.Printf(", %d without a video link", noID)
+		}
+		fmt.Println()
+	}
+
+	var current, removed []Video
+	for _, key := range latestOrder {
+		current = append(current, *merged[key])
+	}
+	for _, key := range order {
+		if !latest[key] {
+			removed = append(removed, *merged[key])
+		}
+	}
+	sort.SliceStable(removed, func(i, j int) bool {
+		return removed[i].LastSeen > removed[j].LastSeen
+	})
+
+	return current, removed, nil
+}
+
+// saveVideos saves a flat list of videos to a JSON file
+func saveVideos(filename string, videos []Video) error {
+	bytes, err := json.MarshalIndent(videos, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, bytes, 0644)
+}
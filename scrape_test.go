@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectScrapeFilesSkipsOtherJSON(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"scrape-2024-05-01.json":     `[{"title": "MySQL Tutorial", "link": "https://www.youtube.com/watch?v=yPu6qV5byu4"}]`,
+		"empty.json":                 `[]`,
+		"rules.json":                 `{"fields": {"title": 1.0}, "categories": []}`,
+		"token.json":                 `{"access_token": "x"}`,
+		"categorized_videos.json":    `[{"category": "Other", "videos": []}]`,
+		"suggested_rules.json":       `{"addCategories": []}`,
+		"notes.txt":                  `[{"title": "not json"}]`,
+		"categorized_videos_v2.json": `{"schemaVersion": 2, "videos": []}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := collectScrapeFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "empty.json"), filepath.Join(dir, "scrape-2024-05-01.json")}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMergeScrapes(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "scrape-2024-04-01.json")
+	newer := filepath.Join(dir, "scrape-2024-05-01.json")
+	os.WriteFile(older, []byte(`[
+		{"title": "Old title", "link": "https://www.youtube.com/watch?v=aaaaaaaaaaa"},
+		{"title": "Watched", "link": "https://www.youtube.com/watch?v=bbbbbbbbbbb"}
+	]`), 0644)
+	os.WriteFile(newer, []byte(`[
+		{"title": "New title", "link": "https://www.youtube.com/watch?v=aaaaaaaaaaa"},
+		{"title": "Added", "link": "https://www.youtube.com/watch?v=ccccccccccc"},
+		{"title": "Added", "link": "https://www.youtube.com/watch?v=ccccccccccc"}
+	]`), 0644)
+
+	current, removed, err := mergeScrapes([]string{newer, older})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) != 2 || len(removed) != 1 {
+		t.Fatalf("got %d current and %d removed videos, want 2 and 1", len(current), len(removed))
+	}
+	if current[0].Title != "New title" || current[0].FirstSeen != "2024-04-01" || current[0].LastSeen != "2024-05-01" {
+		t.Errorf("merged video = %+v", current[0])
+	}
+	if current[1].ID != "ccccccccccc" || current[1].FirstSeen != "2024-05-01" {
+		t.Errorf("added video = %+v", current[1])
+	}
+	if removed[0].ID != "bbbbbbbbbbb" || removed[0].LastSeen != "2024-04-01" {
+		t.Errorf("removed video = %+v", removed[0])
+	}
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// videoIDPattern is the shape of a YouTube video ID, used to keep thumbnail paths safe
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// thumbnailURL returns the standard i.ytimg.com thumbnail for a video
+func thumbnailURL(videoID string) string {
+	return "https://i.ytimg.com/vi/" + videoID + "/mqdefault.jpg"
+}
+
+// dashboard serves the categorized library over HTTP
+type dashboard struct {
+	lib        *Library
+	rules      *Rules
+	addr       string // the address the dashboard listens on
+	thumbnails string // "remote", "cache" or "off"
+	cacheDir   string
+	page       *template.Template
+}
+
+// dashboardFilter is the search and filters from the query string
+type dashboardFilter struct {
+	Query       string
+	Category    string
+	Channel     string
+	MaxMinutes  int
+	MaxOptions  []int
+	Overridden  bool
+	Thumbnails  string
+	Categories  []string
+	Channels    []string
+	ReturnQuery string
+	Total       int
+	Shown       int
+	Results     []CategorizedVideos
+	Excluded    []Video
+	Message     string
+	SyncPlans   []playlistPlan
+	SyncPlanned bool
+}
+
+// categorized runs categorizeVideos over the library exactly as the sort
+// does, returning the videos it categorized and the excluded videos too
+func (d *dashboard) categorized() ([]Video, []CategorizedVideos, []Video, error) {
+	videos, err := d.lib.watchLaterVideos()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	markUnavailable(videos, "")
+
+	overrides, err := d.lib.overrides()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var excluded []Video
+	for _, video := range videos {
+		if overrides[videoKey(video)] == excludeCategory {
+			video.Override = excludeCategory
+			excluded = append(excluded, video)
+		}
+	}
+	return videos, categorizeVideos(videos, d.rules, overrides), excluded, nil
+}
+
+// sameOrigin guards the POST handlers against cross-site requests: the Host
+// must be the address the dashboard listens on, and a browser's Origin (or
+// Referer) must point back at that host
+func (d *dashboard) sameOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.ownHost(r.Host) {
+			http.Error(w, "unexpected Host "+r.Host, http.StatusForbidden)
+			return
+		}
+		source := r.Header.Get("Origin")
+		if source == "" {
+			source = r.Header.Get("Referer")
+		}
+		if source != "" {
+			u, err := url.Parse(source)
+			if err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// ownHost reports whether a Host header names the dashboard. When it listens
+// on every interface, any host on the right port is accepted.
+func (d *dashboard) ownHost(host string) bool {
+	if host == d.addr {
+		return true
+	}
+	listenHost, listenPort, err := net.SplitHostPort(d.addr)
+	if err != nil {
+		return false
+	}
+	reqHost, reqPort, err := net.SplitHostPort(host)
+	if err != nil || reqPort != listenPort {
+		return false
+	}
+	switch listenHost {
+	case "", "0.0.0.0", "::":
+		return true
+	case "localhost", "127.0.0.1", "::1":
+		return reqHost == "localhost" || reqHost == "127.0.0.1" || reqHost == "::1"
+	}
+	return strings.EqualFold(reqHost, listenHost)
+}
+
+// matches reports whether a video passes the search and filters
+func (f *dashboardFilter) matches(category string, video Video) bool {
+	if f.Category != "" && f.Category != category {
+		return false
+	}
+	if f.Channel != "" && f.Channel != video.Channel {
+		return false
+	}
+	if f.MaxMinutes > 0 && (video.DurationSeconds == 0 || video.DurationSeconds > f.MaxMinutes*60) {
+		return false
+	}
+	if f.Overridden && video.Override == "" {
+		return false
+	}
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(video.Title), query) && !strings.Contains(strings.ToLower(video.Channel), query) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleIndex renders the dashboard page
+func (d *dashboard) handleIndex(w http.ResponseWriter, r *http.Request) {
+	d.render(w, r, "", nil)
+}
+
+// render builds the filtered view and executes the page template
+func (d *dashboard) render(w http.ResponseWriter, r *http.Request, message string, plans []playlistPlan) {
+	_, categorizedVideos, excluded, err := d.categorized()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &dashboardFilter{
+		Query:       query.Get("q"),
+		Category:    query.Get("category"),
+		Channel:     query.Get("channel"),
+		Overridden:  query.Get("overridden") != "",
+		MaxOptions:  []int{10, 15, 30, 60},
+		ReturnQuery: r.URL.RawQuery,
+		Thumbnails:  d.thumbnails,
+		Categories:  append(d.rules.categoryNames(), otherCategory),
+		Message:     message,
+		SyncPlans:   plans,
+		SyncPlanned: plans != nil,
+	}
+	filter.MaxMinutes, _ = strconv.Atoi(query.Get("max"))
+
+	channels := map[string]bool{}
+	for _, catVideos := range categorizedVideos {
+		shown := CategorizedVideos{Category: catVideos.Category}
+		for _, video := range catVideos.Videos {
+			filter.Total++
+			if video.Channel != "" && !channels[video.Channel] {
+				channels[video.Channel] = true
+				filter.Channels = append(filter.Channels, video.Channel)
+			}
+			if filter.matches(catVideos.Category, video) {
+				shown.Videos = append(shown.Videos, video)
+			}
+		}
+		filter.Shown += len(shown.Videos)
+		if len(shown.Videos) > 0 {
+			filter.Results = append(filter.Results, shown)
+		}
+	}
+	for _, video := range excluded {
+		if filter.matches(excludeCategory, video) {
+			filter.Excluded = append(filter.Excluded, video)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := d.page.Execute(w, filter); err != nil {
+		log.Printf("Error rendering dashboard: %v", err)
+	}
+}
+
+// handleSetCategory saves an override from the form or JSON API
+func (d *dashboard) handleSetCategory(w http.ResponseWriter, r *http.Request) {
+	videoID := r.PathValue("id")
+	category := r.FormValue("category")
+
+	var err error
+	switch {
+	case category == "" || category == "rules":
+		_, err = d.lib.clearOverride(videoID)
+	case validOverride(d.rules, category):
+		err = d.lib.setOverride(videoID, category)
+	default:
+		http.Error(w, fmt.Sprintf("unknown category %q", category), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, map[string]string{"video": videoID, "override": category})
+		return
+	}
+	// Go back to the same filtered view
+	values, _ := url.ParseQuery(r.FormValue("return"))
+	http.Redirect(w, r, "/?"+values.Encode(), http.StatusSeeOther)
+}
+
+// handleCategories returns the categorized library as JSON, grouped by category
+// like version 1 of categorized_videos.json
+func (d *dashboard) handleCategories(w http.ResponseWriter, r *http.Request) {
+	_, categorizedVideos, _, err := d.categorized()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, categorizedVideos)
+}
+
+// handleDryRun plans a playlist sync without calling YouTube
+func (d *dashboard) handleDryRun(w http.ResponseWriter, r *http.Request) {
+	videos, categorizedVideos, _, err := d.categorized()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plans, err := planPlaylistSync(d.lib, d.rules, videos, categorizedVideos, buildSmartPlaylists(d.rules, categorizedVideos))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, plans)
+		return
+	}
+	if plans == nil {
+		plans = []playlistPlan{}
+	}
+	d.render(w, r, "Dry run: nothing was sent to YouTube", plans)
+}
+
+// handleThumbnail serves thumbnails from the local cache, downloading them on first use
+func (d *dashboard) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	videoID := strings.TrimSuffix(r.PathValue("file"), ".jpg")
+	if !videoIDPattern.MatchString(videoID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := filepath.Join(d.cacheDir, videoID+".jpg")
+	if _, err := os.Stat(path); err != nil {
+		if err := downloadThumbnail(videoID, path); err != nil {
+			log.Printf("Error caching thumbnail for %s: %v", videoID, err)
+			http.NotFound(w, r)
+			return
+		}
+	}
+	http.ServeFile(w, r, path)
+}
+
+// downloadThumbnail fetches a video thumbnail into the cache directory
+func downloadThumbnail(videoID, path string) error {
+	resp, err := http.Get(thumbnailURL(videoID))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Error writing JSON: %v", err)
+	}
+}
+
+// runServe implements the `serve` command: a local web dashboard for the library
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	thumbnails := flags.String("thumbnails", "remote", `thumbnails: "remote" loads them from i.ytimg.com, "cache" downloads them once into -thumbnail-dir so they work offline, "off" hides them`)
+	cacheDir := flags.String("thumbnail-dir", "thumbnails", "directory for cached thumbnails")
+	flags.Parse(args)
+
+	switch *thumbnails {
+	case "remote", "cache", "off":
+	default:
+		log.Fatalf("Unknown -thumbnails mode %q, expected remote, cache or off", *thumbnails)
+	}
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	d := &dashboard{
+		lib:        lib,
+		rules:      rules,
+		addr:       *addr,
+		thumbnails: *thumbnails,
+		cacheDir:   *cacheDir,
+		page: template.Must(template.New("dashboard").Funcs(template.FuncMap{
+			"duration":     formatDuration,
+			"thumbnailURL": thumbnailURL,
+			"join":         strings.Join,
+		}).Parse(dashboardTemplate)),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", d.handleIndex)
+	mux.HandleFunc("GET /api/categories", d.handleCategories)
+	mux.HandleFunc("POST /videos/{id}/category", d.sameOrigin(d.handleSetCategory))
+	mux.HandleFunc("POST /api/videos/{id}/category", d.sameOrigin(d.handleSetCategory))
+	mux.HandleFunc("POST /sync/dry-run", d.sameOrigin(d.handleDryRun))
+	mux.HandleFunc("POST /api/sync/dry-run", d.sameOrigin(d.handleDryRun))
+	mux.HandleFunc("GET /thumbnails/{file}", d.handleThumbnail)
+
+	fmt.Printf("Serving the library on http://%s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// dashboardTemplate is the single page rendered by the dashboard
+const dashboardTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Watch Later library</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 0 2rem 2rem; color: #222; }
+header { position: sticky; top: 0; background: #fff; padding: 1rem 0; border-bottom: 1px solid #ddd; }
+form.filters { display: flex; gap: .5rem; flex-wrap: wrap; align-items: center; }
+.message { background: #fff4c2; padding: .5rem 1rem; margin-top: 1rem; }
+h2 { margin-top: 2rem; }
+.videos { display: grid; grid-template-columns: repeat(auto-fill, minmax(260px, 1fr)); gap: 1rem; }
+.video { border: 1px solid #ddd; border-radius: 6px; padding: .5rem; }
+.video img { width: 100%; aspect-ratio: 16 / 9; object-fit: cover; background: #eee; }
+.video a { color: inherit; text-decoration: none; font-weight: 600; }
+.meta, .keywords { color: #666; font-size: .85rem; }
+.override { color: #b35c00; }
+.video form { margin-top: .5rem; display: flex; gap: .25rem; }
+.video select { flex: 1; min-width: 0; }
+</style>
+</head>
+<body>
+<header>
+<form class="filters" method="get" action="/">
+  <input type="search" name="q" value="{{.Query}}" placeholder="Search titles and channels">
+  <select name="category">
+    <option value="">All categories</option>
+    {{range .Categories}}<option{{if eq . $.Category}} selected{{end}}>{{.}}</option>{{end}}
+  </select>
+  <select name="channel">
+    <option value="">All channels</option>
+    {{range .Channels}}<option{{if eq . $.Channel}} selected{{end}}>{{.}}</option>{{end}}
+  </select>
+  <select name="max">
+    <option value="">Any length</option>
+    {{range .MaxOptions}}<option value="{{.}}"{{if eq . $.MaxMinutes}} selected{{end}}>Under {{.}} min</option>{{end}}
+  </select>
+  <label><input type="checkbox" name="overridden" value="1"{{if .Overridden}} checked{{end}}> Overridden only</label>
+  <button type="submit">Filter</button>
+  <span>{{.Shown}} of {{.Total}} videos</span>
+</form>
+<form method="post" action="/sync/dry-run" style="margin-top:.5rem"><button type="submit">Dry-run sync</button></form>
+{{if .Message}}<div class="message">{{.Message}}</div>{{end}}
+</header>
+
+{{if .SyncPlanned}}
+<section>
+<h2>Sync plan</h2>
+{{range .SyncPlans}}
+<h3>{{if .PlaylistID}}Update{{else}}Create{{end}} “{{.Title}}”: {{len .Add}} to add, {{len .Remove}} to remove, {{.Existing}} already added</h3>
+<ul>{{range .Add}}<li>+ <a href="{{.Link}}">{{.Title}}</a></li>{{end}}{{range .Remove}}<li>− <a href="{{.Link}}">{{.Title}}</a></li>{{end}}</ul>
+{{else}}<p>Nothing to sync.</p>{{end}}
+</section>
+{{end}}
+
+{{range .Results}}
+<section>
+<h2>{{.Category}} ({{len .Videos}})</h2>
+<div class="videos">
+{{range .Videos}}
+<div class="video">
+  {{if eq $.Thumbnails "remote"}}<img loading="lazy" src="{{thumbnailURL .ID}}" alt="">{{end}}
+  {{if eq $.Thumbnails "cache"}}<img loading="lazy" src="/thumbnails/{{.ID}}.jpg" alt="">{{end}}
+  <a href="{{.Link}}" target="_blank" rel="noopener">{{.Title}}</a>
+  <div class="meta">{{if .Channel}}{{.Channel}} · {{end}}{{duration .DurationSeconds}}{{if .Age}} · {{.Age}}{{end}}</div>
+  {{if .MatchedKeywords}}<div class="keywords">Matched: {{join .MatchedKeywords ", "}}</div>{{end}}
+  {{if .Override}}<div class="override">Override: {{.Override}}</div>{{end}}
+  {{if .Unavailable}}<div class="override">Unavailable: {{.Unavailable}}</div>{{end}}
+  <form method="post" action="/videos/{{.ID}}/category">
+    <input type="hidden" name="return" value="{{$.ReturnQuery}}">
+    <select name="category">
+      <option value="rules">Use rules</option>
+      {{$override := .Override}}
+      {{range $.Categories}}<option{{if eq . $override}} selected{{end}}>{{.}}</option>{{end}}
+      <option value="exclude"{{if eq .Override "exclude"}} selected{{end}}>Exclude</option>
+    </select>
+    <button type="submit">Save</button>
+  </form>
+</div>
+{{end}}
+</div>
+</section>
+{{end}}
+
+{{if .Excluded}}
+<section>
+<h2>Excluded ({{len .Excluded}})</h2>
+<ul>
+{{range .Excluded}}
+<li>
+  <a href="{{.Link}}" target="_blank" rel="noopener">{{.Title}}</a>{{if .Channel}} · {{.Channel}}{{end}}
+  <form method="post" action="/videos/{{.ID}}/category" style="display:inline">
+    <input type="hidden" name="return" value="{{$.ReturnQuery}}">
+    <input type="hidden" name="category" value="rules">
+    <button type="submit">Use rules again</button>
+  </form>
+</li>
+{{end}}
+</ul>
+</section>
+{{end}}
+</body>
+</html>
+`
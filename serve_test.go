@@ -0,0 +1,99 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func testDashboard(t *testing.T) *dashboard {
+	t.Helper()
+	rules := &Rules{Fields: map[string]float64{"title": 1}, Categories: []CategoryRule{{Name: "Kubernetes", Keywords: []string{"helm"}}}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	return &dashboard{
+		lib:        testLibrary(t),
+		rules:      rules,
+		addr:       "localhost:8080",
+		thumbnails: "off",
+		page: template.Must(template.New("dashboard").Funcs(template.FuncMap{
+			"duration":     formatDuration,
+			"thumbnailURL": thumbnailURL,
+			"join":         strings.Join,
+		}).Parse(dashboardTemplate)),
+	}
+}
+
+func TestSameOriginRefusesCrossSitePosts(t *testing.T) {
+	d := testDashboard(t)
+	handler := d.sameOrigin(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		host    string
+		headers map[string]string
+		want    int
+	}{
+		{"no origin", "localhost:8080", nil, http.StatusNoContent},
+		{"same origin", "localhost:8080", map[string]string{"Origin": "http://localhost:8080"}, http.StatusNoContent},
+		{"loopback address", "127.0.0.1:8080", map[string]string{"Origin": "http://127.0.0.1:8080"}, http.StatusNoContent},
+		{"same referer", "localhost:8080", map[string]string{"Referer": "http://localhost:8080/?q=go"}, http.StatusNoContent},
+		{"other origin", "localhost:8080", map[string]string{"Origin": "https://evil.example"}, http.StatusForbidden},
+		{"other referer", "localhost:8080", map[string]string{"Referer": "https://evil.example/page"}, http.StatusForbidden},
+		{"rebound host", "evil.example:8080", map[string]string{"Origin": "http://evil.example:8080"}, http.StatusForbidden},
+		{"other port", "localhost:9090", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/sync/dry-run", nil)
+			r.Host = tt.host
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDashboardListsExcludedVideos(t *testing.T) {
+	d := testDashboard(t)
+	excluded := testVideo("ccccccccccc", "Cat video")
+	if err := d.lib.importScrape([]Video{testVideo("aaaaaaaaaaa", "Helm charts"), excluded}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.lib.setOverride(excluded.ID, excludeCategory); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	d.handleIndex(w, httptest.NewRequest("GET", "/", nil))
+	page := w.Body.String()
+	if !strings.Contains(page, "Excluded (1)") || !strings.Contains(page, "Cat video") {
+		t.Fatalf("excluded video missing from the dashboard")
+	}
+
+	// Clearing the exclusion puts the video back under the rules
+	form := url.Values{"category": {"rules"}}
+	r := httptest.NewRequest("POST", "/videos/"+excluded.ID+"/category", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetPathValue("id", excluded.ID)
+	d.handleSetCategory(httptest.NewRecorder(), r)
+
+	overrides, err := d.lib.overrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := overrides[excluded.ID]; ok {
+		t.Errorf("exclusion was not cleared")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// categoryPlaceholder is replaced with the category name in per-category smart playlist names
+const categoryPlaceholder = "{category}"
+
+// SmartPlaylist is a generated playlist of videos within a duration range,
+// optionally limited to some categories or repeated for every category
+type SmartPlaylist struct {
+	Name string `json:"name"`
+
+	// MinMinutes is inclusive and MaxMinutes exclusive; zero means no limit
+	MinMinutes float64 `json:"minMinutes,omitempty"`
+	MaxMinutes float64 `json:"maxMinutes,omitempty"`
+
+	// PerCategory creates one playlist per category (or per entry in
+	// Categories), with {category} in Name replaced by the category name
+	PerCategory bool `json:"perCategory,omitempty"`
+
+	// Categories limits the playlist to videos in these categories and
+	// their children
+	Categories []string `json:"categories,omitempty"`
+}
+
+// validate checks a smart playlist's name and limits against the rules' categories
+func (s SmartPlaylist) validate(categories []string) error {
+	switch {
+	case s.Name == "":
+		return fmt.Errorf("smart playlist with no name")
+	case s.MinMinutes < 0 || s.MaxMinutes < 0:
+		return fmt.Errorf("smart playlist %q: durations can't be negative", s.Name)
+	case s.MaxMinutes > 0 && s.MinMinutes >= s.MaxMinutes:
+		return fmt.Errorf("smart playlist %q: minMinutes must be less than maxMinutes", s.Name)
+	case s.PerCategory && !strings.Contains(s.Name, categoryPlaceholder):
+		return fmt.Errorf("smart playlist %q: perCategory names must contain %s", s.Name, categoryPlaceholder)
+	case containsString(categories, s.Name):
+		return fmt.Errorf("smart playlist %q has the same name as a category", s.Name)
+	case s.Name == todayPlaylist:
+		return fmt.Errorf("smart playlist %q: the name is reserved for the watch plan", s.Name)
+	}
+	for _, category := range s.Categories {
+		if category != otherCategory && !containsString(categories, category) {
+			return fmt.Errorf("smart playlist %q: unknown category %q", s.Name, category)
+		}
+	}
+	return nil
+}
+
+// fits reports whether a video's duration is within the playlist's range.
+// Videos with no known duration never fit.
+func (s SmartPlaylist) fits(video Video) bool {
+	if video.DurationSeconds <= 0 {
+		return false
+	}
+	minutes := float64(video.DurationSeconds) / 60
+	if minutes < s.MinMinutes {
+		return false
+	}
+	return s.MaxMinutes == 0 || minutes < s.MaxMinutes
+}
+
+// buildSmartPlaylists fills the smart playlists from the categorized videos.
+// Each result uses the playlist name as its Category, so it is synced and
+// tracked in the library exactly like a category playlist.
+func buildSmartPlaylists(rules *Rules, categorizedVideos []CategorizedVideos) []CategorizedVideos {
+	var playlists []CategorizedVideos
+	for _, smart := range rules.SmartPlaylists {
+		categories := rules.withDescendants(smart.Categories)
+		if smart.PerCategory {
+			for _, catVideos := range categorizedVideos {
+				if len(categories) > 0 && !containsString(categories, catVideos.Category) {
+					continue
+				}
+				playlist := CategorizedVideos{Category: strings.ReplaceAll(smart.Name, categoryPlaceholder, catVideos.Category)}
+				for _, video := range catVideos.Videos {
+					if smart.fits(video) {
+						playlist.Videos = append(playlist.Videos, video)
+					}
+				}
+				playlists = append(playlists, playlist)
+			}
+			continue
+		}
+
+		playlist := CategorizedVideos{Category: smart.Name}
+		for _, catVideos := range categorizedVideos {
+			if len(categories) > 0 && !containsString(categories, catVideos.Category) {
+				continue
+			}
+			for _, video := range catVideos.Videos {
+				if smart.fits(video) {
+					playlist.Videos = append(playlist.Videos, video)
+				}
+			}
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists
+}
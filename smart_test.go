@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+// minutesVideo is a video of the given length
+func minutesVideo(id string, seconds int) Video {
+	video := testVideo(id, id)
+	video.DurationSeconds = seconds
+	return video
+}
+
+func TestSmartPlaylistFits(t *testing.T) {
+	tests := []struct {
+		name    string
+		smart   SmartPlaylist
+		seconds int
+		want    bool
+	}{
+		{"no limits", SmartPlaylist{}, 60, true},
+		{"unknown duration", SmartPlaylist{}, 0, false},
+		{"under the maximum", SmartPlaylist{MaxMinutes: 10}, 9*60 + 59, true},
+		{"maximum is exclusive", SmartPlaylist{MaxMinutes: 10}, 10 * 60, false},
+		{"minimum is inclusive", SmartPlaylist{MinMinutes: 10, MaxMinutes: 30}, 10 * 60, true},
+		{"under the minimum", SmartPlaylist{MinMinutes: 10, MaxMinutes: 30}, 9 * 60, false},
+		{"no maximum", SmartPlaylist{MinMinutes: 60}, 3 * 3600, true},
+		{"fractional minutes", SmartPlaylist{MaxMinutes: 0.5}, 29, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.smart.fits(minutesVideo("aaaaaaaaaaa", tt.seconds)); got != tt.want {
+				t.Errorf("fits(%ds) = %v, want %v", tt.seconds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartPlaylistValidate(t *testing.T) {
+	categories := []string{"Kubernetes"}
+	tests := []struct {
+		name  string
+		smart SmartPlaylist
+		ok    bool
+	}{
+		{"valid", SmartPlaylist{Name: "Under 10 minutes", MaxMinutes: 10}, true},
+		{"no name", SmartPlaylist{MaxMinutes: 10}, false},
+		{"negative", SmartPlaylist{Name: "x", MinMinutes: -1}, false},
+		{"empty range", SmartPlaylist{Name: "x", MinMinutes: 30, MaxMinutes: 10}, false},
+		{"per category without placeholder", SmartPlaylist{Name: "Short", PerCategory: true}, false},
+		{"same name as a category", SmartPlaylist{Name: "Kubernetes"}, false},
+		{"unknown category", SmartPlaylist{Name: "x", Categories: []string{"Cooking"}}, false},
+		{"Other is a category", SmartPlaylist{Name: "x", Categories: []string{otherCategory}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.smart.validate(categories); (err == nil) != tt.ok {
+				t.Errorf("validate = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBuildSmartPlaylists(t *testing.T) {
+	rules := &Rules{
+		Categories: []CategoryRule{{Name: "Cloud"}, {Name: "AWS", Parent: "Cloud"}, {Name: "Databases"}},
+		SmartPlaylists: []SmartPlaylist{
+			{Name: "Under 10 minutes", MaxMinutes: 10},
+			{Name: "Cloud deep dives", MinMinutes: 60, Categories: []string{"Cloud"}},
+			{Name: "{category} – under 15 min", MaxMinutes: 15, PerCategory: true, Categories: []string{"Cloud", "Databases"}},
+		},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	categorized := []CategorizedVideos{
+		{Category: "Cloud", Videos: []Video{minutesVideo("cloud-short", 5*60), minutesVideo("cloud-long", 2*3600)}},
+		{Category: "AWS", Videos: []Video{minutesVideo("aws-short", 12*60), minutesVideo("aws-long", 90*60)}},
+		{Category: "Databases", Videos: []Video{minutesVideo("db-short", 8*60), minutesVideo("db-unknown", 0)}},
+		{Category: otherCategory, Videos: []Video{minutesVideo("other-short", 3*60)}},
+	}
+
+	want := map[string][]string{
+		"Under 10 minutes":         {"cloud-short", "db-short", "other-short"},
+		"Cloud deep dives":         {"cloud-long", "aws-long"},
+		"Cloud – under 15 min":     {"cloud-short"},
+		"AWS – under 15 min":       {"aws-short"},
+		"Databases – under 15 min": {"db-short"},
+	}
+	playlists := buildSmartPlaylists(rules, categorized)
+	if len(playlists) != len(want) {
+		t.Fatalf("got %d playlists, want %d", len(playlists), len(want))
+	}
+	for _, playlist := range playlists {
+		ids, ok := want[playlist.Category]
+		if !ok {
+			t.Errorf("unexpected playlist %q", playlist.Category)
+			continue
+		}
+		// Videos keep the order of the categorized videos
+		if len(playlist.Videos) != len(ids) {
+			t.Errorf("%s has %d videos, want %v", playlist.Category, len(playlist.Videos), ids)
+			continue
+		}
+		for i, video := range playlist.Videos {
+			if video.ID != ids[i] {
+				t.Errorf("%s video %d = %s, want %s", playlist.Category, i, video.ID, ids[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// backlogStats summarises a group of videos
+type backlogStats struct {
+	Name            string
+	Videos          int
+	Seconds         int
+	KnownDurations  int
+	MedianSeconds   int
+	durationSamples []int
+}
+
+// add counts a video towards the stats
+func (s *backlogStats) add(video Video) {
+	s.Videos++
+	if video.DurationSeconds > 0 {
+		s.Seconds += video.DurationSeconds
+		s.KnownDurations++
+		s.durationSamples = append(s.durationSamples, video.DurationSeconds)
+	}
+}
+
+// finish works out the median once every video has been added
+func (s *backlogStats) finish() {
+	s.MedianSeconds = median(s.durationSamples)
+}
+
+// median returns the median of the values, or 0 if there are none
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// formatWatchTime renders a total watch time as e.g. "3d 4h 12m" or "45m"
+func formatWatchTime(seconds int) string {
+	d := time.Duration(seconds) * time.Second
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// printStats prints the backlog report for the categorized videos
+func printStats(categorizedVideos []CategorizedVideos, parents map[string]string, daily time.Duration, top int) {
+	total := backlogStats{Name: "Total"}
+	var perCategory []backlogStats
+	channels := make(map[string]*backlogStats)
+	var all []Video
+
+	for _, catVideos := range categorizedVideos {
+		stats := backlogStats{Name: categoryPath(parents, catVideos.Category)}
+		for _, video := range catVideos.Videos {
+			stats.add(video)
+			total.add(video)
+			all = append(all, video)
+
+			if video.Channel != "" {
+				if channels[video.Channel] == nil {
+					channels[video.Channel] = &backlogStats{Name: video.Channel}
+				}
+				channels[video.Channel].add(video)
+			}
+		}
+		stats.finish()
+		perCategory = append(perCategory, stats)
+	}
+	total.finish()
+
+	fmt.Printf("Videos: %d (%d with a known duration)\n", total.Videos, total.KnownDurations)
+	fmt.Printf("Total watch time: %s\n", formatWatchTime(total.Seconds))
+	fmt.Printf("Median length: %s\n", formatDuration(total.MedianSeconds))
+	if daily > 0 {
+		days := float64(total.Seconds) / daily.Seconds()
+		fmt.Printf("Backlog at %s a day: %.0f days (about %.1f weeks)\n", daily, math.Ceil(days), days/7)
+	}
+
+	// Per category, biggest first
+	sort.SliceStable(perCategory, func(i, j int) bool {
+		return perCategory[i].Seconds > perCategory[j].Seconds
+	})
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Category\tVideos\tWatch time\tMedian\tShare")
+	for _, stats := range perCategory {
+		if stats.Videos == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%.0f%%\n", stats.Name, stats.Videos, formatWatchTime(stats.Seconds),
+			formatDuration(stats.MedianSeconds), 100*float64(stats.Videos)/float64(max(total.Videos, 1)))
+	}
+	w.Flush()
+
+	// Parent categories including everything under them
+	rolled := rollUp(parents, categorizedVideos)
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := false
+	for _, catVideos := range categorizedVideos {
+		if !hasChildren(parents, catVideos.Category) || len(rolled[catVideos.Category]) == 0 {
+			continue
+		}
+		if !header {
+			fmt.Println()
+			fmt.Fprintln(w, "With subcategories\tVideos\tWatch time\tShare")
+			header = true
+		}
+		videos := rolled[catVideos.Category]
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.0f%%\n", categoryPath(parents, catVideos.Category), len(videos),
+			formatWatchTime(categoryWatchTime(videos)), 100*float64(len(videos))/float64(max(total.Videos, 1)))
+	}
+	w.Flush()
+
+	for _, stats := range perCategory {
+		if stats.Name == otherCategory {
+			fmt.Printf("\n%q holds %.0f%% of videos and %.0f%% of watch time\n", otherCategory,
+				100*float64(stats.Videos)/float64(max(total.Videos, 1)), 100*float64(stats.Seconds)/float64(max(total.Seconds, 1)))
+		}
+	}
+
+	// Top channels by number of videos
+	var channelStats []*backlogStats
+	for _, stats := range channels {
+		channelStats = append(channelStats, stats)
+	}
+	sort.Slice(channelStats, func(i, j int) bool {
+		if channelStats[i].Videos != channelStats[j].Videos {
+			return channelStats[i].Videos > channelStats[j].Videos
+		}
+		return channelStats[i].Name < channelStats[j].Name
+	})
+	if len(channelStats) > 0 {
+		fmt.Println("\nTop channels:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, stats := range channelStats[:min(top, len(channelStats))] {
+			fmt.Fprintf(w, "  %s\t%d videos\t%s\n", stats.Name, stats.Videos, formatWatchTime(stats.Seconds))
+		}
+		w.Flush()
+	}
+
+	// Oldest items: longest in Watch Later, then oldest upload
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].FirstSeen != all[j].FirstSeen {
+			return all[i].FirstSeen < all[j].FirstSeen
+		}
+		return all[i].PublishedAt < all[j].PublishedAt
+	})
+	if len(all) > 0 {
+		fmt.Println("\nOldest items:")
+		for _, video := range all[:min(top, len(all))] {
+			fmt.Printf("  %s  %s (%s)\n", orDefault(video.FirstSeen, "?"), video.Title, formatDuration(video.DurationSeconds))
+		}
+	}
+}
+
+// runStats implements the `stats` command
+func runStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	daily := flags.Duration("daily", time.Hour, "daily viewing budget used to work out how many days of backlog there are")
+	top := flags.Int("top", 10, "number of channels and oldest items to list")
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		log.Fatalf("Error reading overrides from library: %v", err)
+	}
+
+	// Deleted and private videos can't be watched so don't count towards the backlog
+	if unavailable := markUnavailable(videos, ""); len(unavailable) > 0 {
+		var watchable []Video
+		for _, video := range videos {
+			if video.Unavailable == "" {
+				watchable = append(watchable, video)
+			}
+		}
+		fmt.Printf("Leaving out %d unavailable videos\n", len(unavailable))
+		videos = watchable
+	}
+
+	printStats(categorizeVideos(videos, rules, overrides), rules.parents(), *daily, *top)
+}
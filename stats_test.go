@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+		want   int
+	}{
+		{"empty", nil, 0},
+		{"one", []int{7}, 7},
+		{"odd count", []int{30, 10, 20}, 20},
+		{"even count", []int{40, 10, 30, 20}, 25},
+		{"even count rounds down", []int{1, 2}, 1},
+		{"duplicates", []int{5, 5, 1, 5}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := append([]int{}, tt.values...)
+			if got := median(values); got != tt.want {
+				t.Errorf("median(%v) = %d, want %d", tt.values, got, tt.want)
+			}
+			for i := range values {
+				if values[i] != tt.values[i] {
+					t.Fatalf("median reordered its input: %v", values)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatWatchTime(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "0m"},
+		{59, "0m"},
+		{45 * 60, "45m"},
+		{3600, "1h 0m"},
+		{2*3600 + 5*60 + 30, "2h 5m"},
+		{24 * 3600, "1d 0h 0m"},
+		{3*24*3600 + 4*3600 + 12*60, "3d 4h 12m"},
+	}
+	for _, tt := range tests {
+		if got := formatWatchTime(tt.seconds); got != tt.want {
+			t.Errorf("formatWatchTime(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestBacklogStats(t *testing.T) {
+	stats := backlogStats{Name: "Kubernetes"}
+	for _, seconds := range []int{600, 0, 1800, 1200} {
+		stats.add(Video{DurationSeconds: seconds})
+	}
+	stats.finish()
+	if stats.Videos != 4 || stats.KnownDurations != 3 || stats.Seconds != 3600 || stats.MedianSeconds != 1200 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
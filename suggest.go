@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// keywordSuggestion is a term that picks out one category's videos
+type keywordSuggestion struct {
+	Keyword string
+
+	// Hits counts videos in the category the term matches, Elsewhere those in
+	// other categories and Other those in Other that it would pull in
+	Hits, Elsewhere, Other int
+}
+
+// precision is the share of the categorized videos the term matches that are
+// in its category, counting one extra miss so a term seen once isn't perfect
+func (s keywordSuggestion) precision() float64 {
+	return float64(s.Hits) / float64(s.Hits+s.Elsewhere+1)
+}
+
+// genericKeyword is an existing keyword that matches across categories
+type genericKeyword struct {
+	Keyword string
+
+	// Matches counts the videos it matches in each category
+	Matches map[string]int
+	Total   int
+}
+
+// keywordMatches reports whether a single keyword matches a video in the
+// fields a category weights and despite its exclusions, the same way the
+// category's own keywords do
+func keywordMatches(keyword string, category CategoryRule, video Video, defaults map[string]float64) bool {
+	category.Keywords = []string{keyword}
+	score, _ := category.score(video, defaults)
+	return score > 0
+}
+
+// candidateTerms returns the distinct terms of the videos that could become
+// keywords, leaving out numbers
+func candidateTerms(videos []Video) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, video := range videos {
+		for _, term := range topicTerms(video) {
+			if !seen[term] && strings.IndexFunc(term, unicode.IsLetter) >= 0 {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// suggestKeywords proposes up to limit new keywords for a category: terms in
+// at least minVideos of its videos and with at least minPrecision of their
+// categorized matches in it. Terms an existing keyword already covers are
+// skipped, as are terms covered by a better suggestion.
+func suggestKeywords(rules *Rules, category CategoryRule, categorized []CategorizedVideos, minVideos int, minPrecision float64, limit int) []keywordSuggestion {
+	var own []Video
+	for _, catVideos := range categorized {
+		if catVideos.Category == category.Name {
+			own = catVideos.Videos
+		}
+	}
+
+	var suggestions []keywordSuggestion
+	for _, term := range candidateTerms(own) {
+		covered := false
+		for _, keyword := range category.Keywords {
+			// Keywords match substrings, so an existing keyword inside the
+			// term already matches everything the term would
+			if strings.Contains(term, strings.ToLower(keyword)) {
+				covered = true
+			}
+		}
+		if covered {
+			continue
+		}
+
+		suggestion := keywordSuggestion{Keyword: term}
+		for _, catVideos := range categorized {
+			for _, video := range catVideos.Videos {
+				if !keywordMatches(term, category, video, rules.Fields) {
+					continue
+				}
+				switch catVideos.Category {
+				case category.Name:
+					suggestion.Hits++
+				case otherCategory:
+					suggestion.Other++
+				default:
+					suggestion.Elsewhere++
+				}
+			}
+		}
+		if suggestion.Hits >= minVideos && suggestion.precision() >= minPrecision {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.precision() != b.precision() {
+			return a.precision() > b.precision()
+		}
+		return a.Hits > b.Hits
+	})
+
+	var picked []keywordSuggestion
+	for _, suggestion := range suggestions {
+		overlaps := false
+		for _, other := range picked {
+			if strings.Contains(suggestion.Keyword, other.Keyword) || strings.Contains(other.Keyword, suggestion.Keyword) {
+				overlaps = true
+			}
+		}
+		if !overlaps {
+			picked = append(picked, suggestion)
+		}
+		if len(picked) == limit {
+			break
+		}
+	}
+	return picked
+}
+
+// genericKeywords returns a category's keywords that match at least minVideos
+// categorized videos but less than maxShare of them in the category itself
+func genericKeywords(rules *Rules, category CategoryRule, categorized []CategorizedVideos, minVideos int, maxShare float64) []genericKeyword {
+	var generic []genericKeyword
+	var checked []string
+	for _, keyword := range category.Keywords {
+		if containsFold(checked, keyword) {
+			continue // the same keyword in another case matches the same videos
+		}
+		checked = append(checked, keyword)
+		found := genericKeyword{Keyword: keyword, Matches: make(map[string]int)}
+		for _, catVideos := range categorized {
+			if catVideos.Category == otherCategory {
+				continue
+			}
+			for _, video := range catVideos.Videos {
+				if keywordMatches(keyword, category, video, rules.Fields) {
+					found.Matches[catVideos.Category]++
+					found.Total++
+				}
+			}
+		}
+		if found.Total >= minVideos && float64(found.Matches[category.Name]) < maxShare*float64(found.Total) {
+			generic = append(generic, found)
+		}
+	}
+	return generic
+}
+
+// runSuggest implements the `suggest` command
+func runSuggest(args []string) {
+	flags := flag.NewFlagSet("suggest", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	minVideos := flags.Int("min-videos", 3, "videos a keyword must match to be suggested or flagged")
+	minPrecision := flags.Float64("min-precision", 0.75, "share of a suggested keyword's matches that must be in its category")
+	maxShare := flags.Float64("generic", 0.5, "flag keywords with less than this share of their matches in their own category")
+	limit := flags.Int("max", 5, "most keywords to suggest per category")
+	output := flags.String("o", "suggested_keywords.json", "file to write the suggestions to, as a rules patch")
+	apply := flags.Bool("apply", false, "change the rules file straight away")
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, err := lib.sortedVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
+	}
+	overrides, err := lib.overrides()
+	if err != nil {
+		log.Fatalf("Error reading overrides from library: %v", err)
+	}
+	categorized := categorizeVideos(videos, rules, overrides)
+	fmt.Printf("Analysing %d categorized videos\n", len(videos))
+
+	patch := rulesPatch{AddKeywords: make(map[string][]string), RemoveKeywords: make(map[string][]string)}
+	for _, category := range rules.Categories {
+		suggestions := suggestKeywords(rules, category, categorized, *minVideos, *minPrecision, *limit)
+		generic := genericKeywords(rules, category, categorized, *minVideos, *maxShare)
+		if len(suggestions) == 0 && len(generic) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n%s\n", category.Name)
+		for _, suggestion := range suggestions {
+			patch.AddKeywords[category.Name] = append(patch.AddKeywords[category.Name], suggestion.Keyword)
+			fmt.Printf("  + %q: %d videos, %d in other categories, would add %d from %s\n",
+				suggestion.Keyword, suggestion.Hits, suggestion.Elsewhere, suggestion.Other, otherCategory)
+		}
+		for _, keyword := range generic {
+			patch.RemoveKeywords[category.Name] = append(patch.RemoveKeywords[category.Name], keyword.Keyword)
+			var spread []string
+			for _, name := range rules.categoryNames() {
+				if n := keyword.Matches[name]; n > 0 && name != category.Name {
+					spread = append(spread, fmt.Sprintf("%s %d", name, n))
+				}
+			}
+			fmt.Printf("  - %q is too generic: %d of %d matches here, also %s\n",
+				keyword.Keyword, keyword.Matches[category.Name], keyword.Total, strings.Join(spread, ", "))
+		}
+	}
+
+	if len(patch.AddKeywords) == 0 && len(patch.RemoveKeywords) == 0 {
+		fmt.Println("No changes to suggest")
+		return
+	}
+	if *apply {
+		if err := patch.apply(rules); err != nil {
+			log.Fatalf("Error applying suggestions: %v", err)
+		}
+		if err := saveRules(*rulesPath, rules); err != nil {
+			log.Fatalf("Error saving rules: %v", err)
+		}
+		fmt.Printf("\nRules saved to %s\n", *rulesPath)
+		return
+	}
+	if err := saveRulesPatch(*output, patch); err != nil {
+		log.Fatalf("Error saving suggestions: %v", err)
+	}
+	fmt.Printf("\nSuggestions saved to %s; trim them, then apply with `go run . patch %s`\n", *output, *output)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// suggestVideos returns Kubernetes videos where helm and pod pick out the
+// category, chart also matches a database video and kubectl is a keyword
+func suggestVideos() []CategorizedVideos {
+	return []CategorizedVideos{
+		{Category: "Kubernetes", Videos: []Video{
+			testVideo("k1", "Helm charts explained"),
+			testVideo("k2", "Helm chart basics"),
+			testVideo("k3", "Helm upgrades"),
+			testVideo("k4", "kubectl tips"),
+			testVideo("k5", "kubectl debugging"),
+			testVideo("k6", "Pod scheduling"),
+			testVideo("k7", "Pod networking"),
+		}},
+		{Category: "Databases", Videos: []Video{
+			testVideo("d1", "Postgres charts"),
+			testVideo("d2", "Postgres indexes"),
+		}},
+		{Category: otherCategory, Videos: []Video{
+			testVideo("o1", "At the helm of a ship"),
+		}},
+	}
+}
+
+func TestSuggestKeywords(t *testing.T) {
+	rules := &Rules{Categories: []CategoryRule{{Name: "Kubernetes", Keywords: []string{"kubectl"}}, {Name: "Databases", Keywords: []string{"postgres"}}}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	categorized := suggestVideos()
+
+	// kubectl is already a keyword, so neither it nor "kubectl tips" is
+	// suggested; "helm chart" is dropped as it overlaps helm, and chart
+	// matches a database video too often
+	tests := []struct {
+		minVideos    int
+		minPrecision float64
+		limit        int
+		want         []string
+	}{
+		{2, 0.6, 5, []string{"helm", "pod"}},
+		{2, 0.6, 1, []string{"helm"}},
+		{2, 0.7, 5, []string{"helm"}},
+		{3, 0.6, 5, []string{"helm"}},
+		{2, 0.4, 5, []string{"helm", "pod", "chart"}},
+		{4, 0.1, 5, nil},
+	}
+	for _, tt := range tests {
+		suggestions := suggestKeywords(rules, rules.Categories[0], categorized, tt.minVideos, tt.minPrecision, tt.limit)
+		var got []string
+		for _, suggestion := range suggestions {
+			got = append(got, suggestion.Keyword)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("suggestKeywords(%d, %v, %d) = %q, want %q", tt.minVideos, tt.minPrecision, tt.limit, got, tt.want)
+		}
+	}
+
+	helm := suggestKeywords(rules, rules.Categories[0], categorized, 2, 0.6, 1)[0]
+	if helm.Hits != 3 || helm.Elsewhere != 0 || helm.Other != 1 {
+		t.Errorf("helm matched %d, %d elsewhere and %d in Other; want 3, 0 and 1", helm.Hits, helm.Elsewhere, helm.Other)
+	}
+}
+
+func TestGenericKeywords(t *testing.T) {
+	kubernetes := CategoryRule{Name: "Kubernetes", Keywords: []string{"Chart", "chart", "helm", "kubectl", "ship"}}
+	rules := &Rules{Categories: []CategoryRule{kubernetes, {Name: "Databases", Keywords: []string{"postgres"}}}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// chart is checked once whatever its case; ship only matches a video in
+	// Other, which doesn't count
+	generic := genericKeywords(rules, rules.Categories[0], suggestVideos(), 1, 0.7)
+	want := []genericKeyword{{Keyword: "Chart", Matches: map[string]int{"Kubernetes": 2, "Databases": 1}, Total: 3}}
+	if !reflect.DeepEqual(generic, want) {
+		t.Errorf("genericKeywords = %+v, want %+v", generic, want)
+	}
+
+	if generic := genericKeywords(rules, rules.Categories[0], suggestVideos(), 4, 0.7); len(generic) != 0 {
+		t.Errorf("with at least 4 videos, genericKeywords = %+v, want none", generic)
+	}
+	if generic := genericKeywords(rules, rules.Categories[0], suggestVideos(), 1, 0.5); len(generic) != 0 {
+		t.Errorf("with a share of 0.5, genericKeywords = %+v, want none", generic)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import "fmt"
+
+// playlistPlan is what a sync would do for one category: create the playlist
+// if it has no PlaylistID yet, remove the videos in Remove and add the videos
+// in Add
+type playlistPlan struct {
+	Category    string  `json:"category"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	PlaylistID  string  `json:"playlistId,omitempty"`
+	Add         []Video `json:"add"`
+	Remove      []Video `json:"remove"`
+	Existing    int     `json:"existing"`
+	Unavailable int     `json:"unavailable"`
+}
+
+// planPlaylistSync works out, for every category except Other and every
+// smart playlist, whether its playlist already exists, which videos have not
+// been added to it yet and which were added but now belong elsewhere, say
+// after an override or exclusion. Only the given videos are ever removed, so
+// videos that have left Watch Later stay in their playlists. Videos marked
+// unavailable are skipped.
+func planPlaylistSync(lib *Library, rules *Rules, videos []Video, categorizedVideos, smartPlaylists []CategorizedVideos) ([]playlistPlan, error) {
+	var plans []playlistPlan
+	for _, catVideos := range categorizedVideos {
+		if catVideos.Category == otherCategory {
+			continue
+		}
+		plan, err := planPlaylist(lib, videos, catVideos, rules.playlistTitle(catVideos.Category), "A playlist of "+catVideos.Category+" videos")
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil {
+			plans = append(plans, *plan)
+		}
+	}
+
+	for _, smart := range smartPlaylists {
+		plan, err := planPlaylist(lib, videos, smart, smart.Category, "A smart playlist of "+smart.Category+" videos")
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil {
+			plans = append(plans, *plan)
+		}
+	}
+	return plans, nil
+}
+
+// planPlaylist plans the sync of a single playlist, returning nil when there
+// is no playlist yet and nothing to put in it
+func planPlaylist(lib *Library, videos []Video, catVideos CategorizedVideos, title, description string) (*playlistPlan, error) {
+	plan := playlistPlan{
+		Category:    catVideos.Category,
+		Title:       title,
+		Description: description,
+		Add:         []Video{},
+		Remove:      []Video{},
+	}
+
+	playlistID, err := lib.playlistID(catVideos.Category)
+	if err != nil {
+		return nil, err
+	}
+	plan.PlaylistID = playlistID
+
+	existing := map[string]bool{}
+	if playlistID != "" {
+		if existing, err = lib.playlistVideos(playlistID); err != nil {
+			return nil, err
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, video := range catVideos.Videos {
+		wanted[video.ID] = true
+		switch {
+		case video.Unavailable != "" || extractVideoID(video.Link) == "":
+			plan.Unavailable++
+		case existing[video.ID]:
+			plan.Existing++
+		default:
+			plan.Add = append(plan.Add, video)
+		}
+	}
+
+	// Videos synced earlier that now belong in another playlist, or none
+	for _, video := range videos {
+		if existing[video.ID] && !wanted[video.ID] {
+			plan.Remove = append(plan.Remove, video)
+			wanted[video.ID] = true
+		}
+	}
+
+	// Don't create a playlist with nothing to put in it
+	if playlistID == "" && len(plan.Add) == 0 {
+		return nil, nil
+	}
+	return &plan, nil
+}
+
+// printSyncPlan prints what a sync would do without calling YouTube
+func printSyncPlan(plans []playlistPlan) {
+	total, removed := 0, 0
+	for _, plan := range plans {
+		action := "update"
+		if plan.PlaylistID == "" {
+			action = "create"
+		}
+		fmt.Printf("[dry run] %s playlist %q: %d videos to add, %d to remove, %d already added, %d unavailable\n", action, plan.Title, len(plan.Add), len(plan.Remove), plan.Existing, plan.Unavailable)
+		for _, video := range plan.Add {
+			fmt.Printf("  + %s (ID: %s)\n", video.Title, video.ID)
+		}
+		for _, video := range plan.Remove {
+			fmt.Printf("  - %s (ID: %s)\n", video.Title, video.ID)
+		}
+		total += len(plan.Add)
+		removed += len(plan.Remove)
+	}
+	fmt.Printf("[dry run] %d videos would be added and %d removed across %d playlists\n", total, removed, len(plans))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// testLibrary opens an empty library in a temporary directory
+func testLibrary(t *testing.T) *Library {
+	t.Helper()
+	lib, err := openLibrary(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lib.Close() })
+	return lib
+}
+
+// testVideo is a video with a link, so it has an ID like a scraped one
+func testVideo(id, title string) Video {
+	return Video{ID: id, Title: title, Link: "https://www.youtube.com/watch?v=" + id}
+}
+
+func TestPlanPlaylistSyncRemovesMovedVideos(t *testing.T) {
+	lib := testLibrary(t)
+	stays := testVideo("aaaaaaaaaaa", "Helm charts")
+	moved := testVideo("bbbbbbbbbbb", "SQL joins")
+	excluded := testVideo("ccccccccccc", "Cat video")
+	watched := testVideo("ddddddddddd", "Watched talk")
+	added := testVideo("eeeeeeeeeee", "Docker basics")
+	videos := []Video{stays, moved, excluded, added}
+	if err := lib.importScrape(append(videos, watched), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lib.savePlaylist("Kubernetes", "PLk8s", "Kubernetes Playlist"); err != nil {
+		t.Fatal(err)
+	}
+	for _, video := range []Video{stays, moved, excluded, watched} {
+		if err := lib.savePlaylistItem("PLk8s", video.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := &Rules{Categories: []CategoryRule{{Name: "Kubernetes"}, {Name: "Databases"}}}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	categorized := []CategorizedVideos{
+		{Category: "Kubernetes", Videos: []Video{stays, added}},
+		{Category: "Databases", Videos: []Video{moved}},
+	}
+
+	plans, err := planPlaylistSync(lib, rules, videos, categorized, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("got %d plans, want 2", len(plans))
+	}
+
+	k8s := plans[0]
+	if k8s.PlaylistID != "PLk8s" || k8s.Existing != 1 {
+		t.Errorf("Kubernetes plan = %+v", k8s)
+	}
+	if len(k8s.Add) != 1 || k8s.Add[0].ID != added.ID {
+		t.Errorf("Kubernetes adds %v, want %s", k8s.Add, added.ID)
+	}
+	// The watched video has left Watch Later, so it stays
+	if len(k8s.Remove) != 2 || k8s.Remove[0].ID != moved.ID || k8s.Remove[1].ID != excluded.ID {
+		t.Errorf("Kubernetes removes %v, want %s and %s", k8s.Remove, moved.ID, excluded.ID)
+	}
+
+	databases := plans[1]
+	if databases.PlaylistID != "" || len(databases.Add) != 1 || len(databases.Remove) != 0 {
+		t.Errorf("Databases plan = %+v", databases)
+	}
+}
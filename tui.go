@@ -0,0 +1,454 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	reviewHeaderStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	reviewSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	reviewChangedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	reviewDimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// ignoredGroup lists the videos the ignore rules leave out, so they can be
+// brought back with an override
+const ignoredGroup = "Ignored"
+
+// reviewItem is a video in the review list along with what the rules decided
+// and any override the user has set
+type reviewItem struct {
+	video        Video
+	ruleCategory string
+	ignored      string // why the ignore rules leave the video out, if they do
+	override     string
+	dirty        bool
+}
+
+// category is where the video ends up: the override if there is one, otherwise the rules
+func (item reviewItem) category() string {
+	if item.override != "" {
+		return item.override
+	}
+	return item.ruleCategory
+}
+
+// reviewModel is the bubbletea model for the review TUI
+type reviewModel struct {
+	lib        *Library
+	items      []reviewItem
+	groups     []string
+	targets    []string // the groups a video can be moved to
+	order      []int    // item indices grouped by category
+	cursor     int      // position in order
+	offset     int      // first visible line of the list
+	width      int
+	height     int
+	picking    bool // choosing a category to move the video to
+	pickCursor int
+	status     string
+	quitArmed  bool
+}
+
+// newReviewModel lists every video where the sort puts it, overrides
+// included. The videos are also categorized with the rules alone, so the rule
+// category and matched keywords can be shown next to any override, and
+// ignored videos are listed in a group of their own.
+func newReviewModel(lib *Library, rules *Rules, videos []Video, overrides map[string]string) *reviewModel {
+	m := &reviewModel{lib: lib, height: 24, width: 80}
+
+	byRules := make(map[string]Video)
+	ruleCategories := make(map[string]string)
+	for _, catVideos := range categorizeVideos(videos, rules, nil) {
+		for _, video := range catVideos.Videos {
+			byRules[videoKey(video)] = video
+			ruleCategories[videoKey(video)] = catVideos.Category
+		}
+	}
+	sorted := make(map[string]Video)
+	for _, catVideos := range categorizeVideos(videos, rules, overrides) {
+		for _, video := range catVideos.Videos {
+			sorted[videoKey(video)] = video
+		}
+	}
+
+	for _, video := range videos {
+		key := videoKey(video)
+		item := reviewItem{video: video, ruleCategory: ruleCategories[key], override: overrides[key]}
+		if ruled, ok := byRules[key]; ok {
+			item.video = ruled
+		} else if reason := rules.Ignore.reason(video); reason != "" {
+			item.ruleCategory, item.ignored = ignoredGroup, reason
+		}
+		if current, ok := sorted[key]; ok {
+			item.video.Override = current.Override
+		}
+		m.items = append(m.items, item)
+	}
+
+	m.targets = append(rules.categoryNames(), otherCategory, excludeCategory)
+	for _, item := range m.items {
+		if item.override != "" && !containsString(m.targets, item.override) {
+			m.targets = append(m.targets, item.override)
+		}
+	}
+	m.groups = append(append([]string{}, m.targets...), ignoredGroup)
+	for _, item := range m.items {
+		if !containsString(m.groups, item.category()) {
+			m.groups = append(m.groups, item.category())
+		}
+	}
+	m.regroup()
+	return m
+}
+
+// regroup rebuilds the display order after a video changes category, keeping the cursor on it
+func (m *reviewModel) regroup() {
+	selected := -1
+	if m.cursor < len(m.order) {
+		selected = m.order[m.cursor]
+	}
+
+	m.order = m.order[:0]
+	for _, group := range m.groups {
+		for i, item := range m.items {
+			if item.category() == group {
+				m.order = append(m.order, i)
+			}
+		}
+	}
+
+	for pos, i := range m.order {
+		if i == selected {
+			m.cursor = pos
+		}
+	}
+}
+
+// current returns the item under the cursor
+func (m *reviewModel) current() *reviewItem {
+	if len(m.order) == 0 {
+		return nil
+	}
+	return &m.items[m.order[m.cursor]]
+}
+
+// setOverride changes the selected video's override and marks it unsaved
+func (m *reviewModel) setOverride(category string) {
+	item := m.current()
+	if item == nil || item.override == category {
+		return
+	}
+	item.override = category
+	item.dirty = true
+	m.quitArmed = false
+	m.regroup()
+}
+
+// unsaved counts the items changed since the last save
+func (m *reviewModel) unsaved() int {
+	n := 0
+	for _, item := range m.items {
+		if item.dirty {
+			n++
+		}
+	}
+	return n
+}
+
+// save writes every changed item to the library as an override (or clears it)
+func (m *reviewModel) save() error {
+	for i := range m.items {
+		item := &m.items[i]
+		if !item.dirty {
+			continue
+		}
+		var err error
+		if item.override == "" {
+			_, err = m.lib.clearOverride(videoKey(item.video))
+		} else {
+			err = m.lib.setOverride(videoKey(item.video), item.override)
+		}
+		if err != nil {
+			return err
+		}
+		item.dirty = false
+	}
+	return nil
+}
+
+func (m *reviewModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+
+	case tea.KeyMsg:
+		if m.picking {
+			return m.updatePicker(msg)
+		}
+
+		m.status = ""
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "q":
+			if m.unsaved() > 0 && !m.quitArmed {
+				m.quitArmed = true
+				m.status = "Unsaved changes: press s to save or q again to quit without saving"
+				return m, nil
+			}
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.order)-1 {
+				m.cursor++
+			}
+		case "tab", "]":
+			m.jumpGroup(1)
+		case "shift+tab", "[":
+			m.jumpGroup(-1)
+		case "m":
+			if item := m.current(); item != nil {
+				m.picking = true
+				m.pickCursor = 0
+				for i, group := range m.targets {
+					if group == item.category() {
+						m.pickCursor = i
+					}
+				}
+			}
+		case "x":
+			if item := m.current(); item != nil {
+				if item.override == excludeCategory {
+					m.setOverride("")
+				} else {
+					m.setOverride(excludeCategory)
+				}
+			}
+		case "r":
+			m.setOverride("")
+		case "s":
+			if err := m.save(); err != nil {
+				m.status = "Error saving overrides: " + err.Error()
+			} else {
+				m.status = "Overrides saved"
+			}
+		}
+	}
+	return m, nil
+}
+
+// updatePicker handles keys while choosing a category for the selected video
+func (m *reviewModel) updatePicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q":
+		m.picking = false
+	case "up", "k":
+		if m.pickCursor > 0 {
+			m.pickCursor--
+		}
+	case "down", "j":
+		if m.pickCursor < len(m.targets)-1 {
+			m.pickCursor++
+		}
+	case "enter":
+		m.picking = false
+		m.setOverride(m.targets[m.pickCursor])
+	}
+	return m, nil
+}
+
+// jumpGroup moves the cursor to the first video of the next or previous category
+func (m *reviewModel) jumpGroup(direction int) {
+	if len(m.order) == 0 {
+		return
+	}
+	category := m.current().category()
+	for pos := m.cursor + direction; pos >= 0 && pos < len(m.order); pos += direction {
+		if next := m.items[m.order[pos]].category(); next != category {
+			// Going backwards, land on the first video of that category
+			for direction < 0 && pos > 0 && m.items[m.order[pos-1]].category() == next {
+				pos--
+			}
+			m.cursor = pos
+			return
+		}
+	}
+}
+
+// groupCounts counts the videos currently in each category
+func (m *reviewModel) groupCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, item := range m.items {
+		counts[item.category()]++
+	}
+	return counts
+}
+
+func (m *reviewModel) View() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %d videos, %d unsaved changes\n\n",
+		reviewHeaderStyle.Render("Review categories"), len(m.items), m.unsaved())
+
+	if m.picking {
+		b.WriteString("Move to category:\n\n")
+		for i, group := range m.targets {
+			line := "  " + group
+			if i == m.pickCursor {
+				line = reviewSelectedStyle.Render("> " + group)
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n" + reviewDimStyle.Render("↑/↓ choose • enter move • esc cancel"))
+		return b.String()
+	}
+
+	// Build the list lines, remembering which line holds the cursor
+	counts := m.groupCounts()
+	var lines []string
+	cursorLine := 0
+	lastGroup := ""
+	for pos, i := range m.order {
+		item := m.items[i]
+		if group := item.category(); group != lastGroup || pos == 0 {
+			lines = append(lines, reviewHeaderStyle.Render(fmt.Sprintf("%s (%d)", group, counts[group])))
+			lastGroup = group
+		}
+
+		line := fmt.Sprintf("  %-*s %8s", max(m.width-14, 20), truncate(item.video.Title, max(m.width-14, 20)), formatDuration(item.video.DurationSeconds))
+		switch {
+		case pos == m.cursor:
+			cursorLine = len(lines)
+			line = reviewSelectedStyle.Render(line)
+		case item.dirty:
+			line = reviewChangedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	// Keep the cursor inside the visible window
+	listHeight := max(m.height-10, 3)
+	if cursorLine < m.offset {
+		m.offset = cursorLine
+	}
+	if cursorLine >= m.offset+listHeight {
+		m.offset = cursorLine - listHeight + 1
+	}
+	end := min(m.offset+listHeight, len(lines))
+	for _, line := range lines[m.offset:end] {
+		b.WriteString(line + "\n")
+	}
+	for i := end - m.offset; i < listHeight; i++ {
+		b.WriteString("\n")
+	}
+
+	// Details of the selected video
+	b.WriteString(reviewDimStyle.Render(strings.Repeat("─", max(m.width, 20))) + "\n")
+	if item := m.current(); item != nil {
+		video := item.video
+		b.WriteString(video.Title + "\n")
+		fmt.Fprintf(&b, "%s • %s • %s\n", orDefault(video.Channel, "unknown channel"), formatDuration(video.DurationSeconds), video.Link)
+		matched := "no keywords matched"
+		switch {
+		case item.ignored != "":
+			matched = item.ignored
+		case len(video.MatchedKeywords) > 0:
+			matched = "matched: " + strings.Join(video.MatchedKeywords, ", ")
+		}
+		fmt.Fprintf(&b, "Rules: %s (%s)", item.ruleCategory, matched)
+		if item.override != "" {
+			fmt.Fprintf(&b, "  Override: %s", item.override)
+		}
+		b.WriteString("\n")
+	}
+
+	if m.status != "" {
+		b.WriteString(reviewChangedStyle.Render(m.status) + "\n")
+	} else {
+		b.WriteString(reviewDimStyle.Render("↑/↓ move • tab/shift+tab next/prev category • m move to category • x exclude • r reset to rules • s save • q quit") + "\n")
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// orDefault returns s, or fallback when s is empty
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// runReview implements the `review` command: an interactive TUI for moving
+// videos between categories and excluding them, saved as overrides
+func runReview(args []string) {
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	rulesPath := flags.String("rules", "rules.json", "categories, keywords and field weights")
+	flags.Parse(args)
+
+	rules, err := loadRules(*rulesPath)
+	if err != nil {
+		log.Fatalf("Error loading rules: %v", err)
+	}
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
+	}
+	if len(videos) == 0 {
+		log.Fatalf("No videos in the library, run the sort first to import a scrape")
+	}
+
+	overrides, err := lib.overrides()
+	if err != nil {
+		log.Fatalf("Error reading overrides from library: %v", err)
+	}
+
+	model := newReviewModel(lib, rules, videos, overrides)
+	if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+		log.Fatalf("Error running review: %v", err)
+	}
+	if n := model.unsaved(); n > 0 {
+		fmt.Printf("Discarded %d unsaved changes\n", n)
+	}
+}
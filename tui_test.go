@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestNewReviewModelShowsOverridesAndIgnoredVideos(t *testing.T) {
+	rules := &Rules{
+		Fields:     map[string]float64{"title": 1},
+		Categories: []CategoryRule{{Name: "Kubernetes", Keywords: []string{"helm"}}, {Name: "Databases", Keywords: []string{"sql"}}},
+		Ignore:     &IgnoreRules{Channels: []string{"Cat Channel"}},
+	}
+	if err := rules.validate(); err != nil {
+		t.Fatal(err)
+	}
+	helm := testVideo("aaaaaaaaaaa", "Helm charts")
+	moved := testVideo("bbbbbbbbbbb", "SQL joins")
+	ignored := testVideo("ccccccccccc", "Cat video")
+	ignored.Channel = "Cat Channel"
+	overrides := map[string]string{moved.ID: "Kubernetes"}
+
+	m := newReviewModel(testLibrary(t), rules, []Video{helm, moved, ignored}, overrides)
+	if len(m.items) != 3 {
+		t.Fatalf("got %d items, want 3", len(m.items))
+	}
+
+	byID := make(map[string]reviewItem)
+	for _, item := range m.items {
+		byID[item.video.ID] = item
+	}
+	if item := byID[moved.ID]; item.ruleCategory != "Databases" || item.category() != "Kubernetes" {
+		t.Errorf("overridden video: rules %q, category %q", item.ruleCategory, item.category())
+	}
+	if item := byID[ignored.ID]; item.category() != ignoredGroup || item.ignored != "channel Cat Channel" {
+		t.Errorf("ignored video: category %q, reason %q", item.category(), item.ignored)
+	}
+	if containsString(m.targets, ignoredGroup) {
+		t.Errorf("%q should not be offered as a category", ignoredGroup)
+	}
+}
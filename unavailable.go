@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// unavailableTitles are the placeholder titles YouTube shows in Watch Later
+// for videos that can no longer be played
+var unavailableTitles = map[string]string{
+	"[deleted video]":     "deleted",
+	"[private video]":     "private",
+	"[unavailable video]": "unavailable",
+}
+
+// unavailableReason explains why a video can't be added to a playlist, or
+// returns "" if it looks fine. The scrape catches placeholder titles and
+// missing links; enriched videos are also checked against the API's privacy,
+// upload status and region restrictions (for region, when it is set).
+func unavailableReason(video Video, region string) string {
+	if reason, ok := unavailableTitles[strings.ToLower(strings.TrimSpace(video.Title))]; ok {
+		return reason + " (placeholder title)"
+	}
+	if extractVideoID(video.Link) == "" {
+		return "no video link in scrape"
+	}
+
+	switch {
+	case video.NotFound:
+		return "not returned by the API (deleted or private)"
+	case video.PrivacyStatus == "private":
+		return "private"
+	case video.UploadStatus == "deleted" || video.UploadStatus == "rejected" || video.UploadStatus == "failed":
+		return "upload " + video.UploadStatus
+	}
+
+	if region != "" {
+		region = strings.ToUpper(region)
+		if containsString(video.RegionBlocked, region) {
+			return "blocked in " + region
+		}
+		if len(video.RegionAllowed) > 0 && !containsString(video.RegionAllowed, region) {
+			return "not available in " + region
+		}
+	}
+	return ""
+}
+
+// markUnavailable sets Unavailable on every video that can't be synced and
+// returns those videos
+func markUnavailable(videos []Video, region string) []Video {
+	var unavailable []Video
+	for i := range videos {
+		videos[i].Unavailable = unavailableReason(videos[i], region)
+		if videos[i].Unavailable != "" {
+			unavailable = append(unavailable, videos[i])
+		}
+	}
+	return unavailable
+}
+
+// printUnavailable lists unavailable videos so they can be removed from Watch Later by hand
+func printUnavailable(unavailable []Video) {
+	fmt.Printf("Unavailable videos to remove from Watch Later: %d\n", len(unavailable))
+	for _, video := range unavailable {
+		fmt.Printf("  %s [%s] %s\n", video.Title, video.Unavailable, video.Link)
+	}
+}
+
+// runUnavailable implements the `unavailable` command: report deleted,
+// private and region-blocked videos still in Watch Later
+func runUnavailable(args []string) {
+	flags := flag.NewFlagSet("unavailable", flag.ExitOnError)
+	libraryPath := flags.String("library", "library.db", "SQLite library holding videos, categories, playlists and sync history")
+	region := flags.String("region", "", "ISO 3166-1 country code to check region restrictions against, e.g. GB")
+	output := flags.String("o", "unavailable_videos.json", "file to write the report to")
+	flags.Parse(args)
+
+	lib, err := openLibrary(*libraryPath)
+	if err != nil {
+		log.Fatalf("Error opening library: %v", err)
+	}
+	defer lib.Close()
+
+	videos, err := lib.watchLaterVideos()
+	if err != nil {
+		log.Fatalf("Error reading videos from library: %v", err)
+	}
+
+	unavailable := markUnavailable(videos, *region)
+	printUnavailable(unavailable)
+	if err := saveVideos(*output, unavailable); err != nil {
+		log.Fatalf("Error saving unavailable videos: %v", err)
+	}
+	fmt.Printf("Report saved to %s\n", *output)
+}
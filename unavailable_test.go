@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestUnavailableReason(t *testing.T) {
+	ok := testVideo("aaaaaaaaaaa", "Helm charts")
+	with := func(change func(*Video)) Video {
+		video := ok
+		change(&video)
+		return video
+	}
+
+	tests := []struct {
+		name   string
+		video  Video
+		region string
+		want   string
+	}{
+		{"available", ok, "", ""},
+		{"deleted placeholder", with(func(v *Video) { v.Title = "[Deleted video]" }), "", "deleted (placeholder title)"},
+		{"private placeholder", with(func(v *Video) { v.Title = " [Private video] " }), "", "private (placeholder title)"},
+		{"unavailable placeholder", with(func(v *Video) { v.Title = "[Unavailable video]" }), "", "unavailable (placeholder title)"},
+		{"no link", with(func(v *Video) { v.Link = "" }), "", "no video link in scrape"},
+		{"link without an ID", with(func(v *Video) { v.Link = "https://www.youtube.com/playlist?list=WL" }), "", "no video link in scrape"},
+		{"not found", with(func(v *Video) { v.NotFound = true }), "", "not returned by the API (deleted or private)"},
+		{"private", with(func(v *Video) { v.PrivacyStatus = "private" }), "", "private"},
+		{"unlisted", with(func(v *Video) { v.PrivacyStatus = "unlisted" }), "", ""},
+		{"upload rejected", with(func(v *Video) { v.UploadStatus = "rejected" }), "", "upload rejected"},
+		{"upload processed", with(func(v *Video) { v.UploadStatus = "processed" }), "", ""},
+		{"blocked in region", with(func(v *Video) { v.RegionBlocked = []string{"DE", "GB"} }), "gb", "blocked in GB"},
+		{"blocked elsewhere", with(func(v *Video) { v.RegionBlocked = []string{"DE"} }), "GB", ""},
+		{"not allowed in region", with(func(v *Video) { v.RegionAllowed = []string{"US"} }), "GB", "not available in GB"},
+		{"allowed in region", with(func(v *Video) { v.RegionAllowed = []string{"US", "GB"} }), "GB", ""},
+		{"region not checked", with(func(v *Video) { v.RegionBlocked = []string{"GB"} }), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unavailableReason(tt.video, tt.region); got != tt.want {
+				t.Errorf("unavailableReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}